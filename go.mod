@@ -4,6 +4,7 @@ require (
 	github.com/neo4j/neo4j-go-driver/v4 v4.2.0
 	github.com/stretchr/testify v1.6.1
 	go.uber.org/automaxprocs v1.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 go 1.13
@@ -54,6 +54,15 @@ function show_client {
   $cmd | jq
 }
 
+# shellcheck disable=SC2034
+# shellcheck disable=SC2086
+function add_members {
+  dbid=$1 ; shift
+  password=$1 ; shift
+  cmd="curl -s -u ignore:ignore -X POST http://localhost:$LISTEN_PORT/api/v1/jobs/$dbid/members"
+  $cmd | jq
+}
+
 # shellcheck disable=SC2034
 # shellcheck disable=SC2086
 function save_client {
@@ -79,6 +88,13 @@ function save_table {
   $cmd | jq -r '.Header, .Rows[] | @csv' > $file
 }
 
+# shellcheck disable=SC2034
+# shellcheck disable=SC2086
+function import_table {
+  cmd="curl -s -u ignore:ignore --data-binary @$TABLE_FILE http://localhost:$LISTEN_PORT/api/v1/import"
+  $cmd | jq
+}
+
 # shellcheck disable=SC2034
 # shellcheck disable=SC2086
 function show_clients {
@@ -144,11 +160,13 @@ usage: latency-benchmark.sh <command> <args>
 where command is:
   help:       print this help
   add_all:    add all databases defined in $TABLE_FILE
+  import:     add all databases defined in $TABLE_FILE with a single request
   add:        add one or more databases by DBID
   remove_all: remove all databases defined in $TABLE_FILE
   remove:     remove one or more databases by DBID
   show_all:   show all databases already added
   show:       show one or more databases by DBID
+  members:    add direct jobs for each cluster member of one or more databases by DBID
   save_all:   save current measurements for all databases already added
   save:       save current measurements for one or more databases by DBID
   list_all:   list all databases defined in $TABLE_FILE
@@ -174,9 +192,15 @@ function main {
   add_all)
     do_all "add_client"
     ;;
+  import)
+    import_table
+    ;;
   add)
     do_all "add_client" "$@"
     ;;
+  members)
+    do_all "add_members" "$@"
+    ;;
   remove_all)
     do_all "remove_client"
     ;;
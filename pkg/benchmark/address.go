@@ -0,0 +1,88 @@
+package benchmark
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// An address strategy decides the Neo4j URI for a database that was added by dbid only. Jobs may instead be added
+// with an explicit URI, which is used as is, so self-managed clusters and local instances can also be benchmarked.
+//
+// ADDRESS_TEMPLATE takes precedence, and may contain '{dbid}', for example 'neo4j://{dbid}.cluster.local:7687'.
+// Otherwise ENVIRONMENT selects the Aura address 'neo4j+s://<dbid>-<environment>.databases.neo4j.io'.
+// Without either, every job needs an explicit URI.
+
+var supportedSchemes = []string{"neo4j", "neo4j+s", "neo4j+ssc", "bolt", "bolt+s", "bolt+ssc"}
+
+type AddressStrategy interface {
+	Address(dbid string) (string, error)
+	String() string
+}
+
+type AuraAddressStrategy struct {
+	environment string
+}
+
+func (a *AuraAddressStrategy) Address(dbid string) (string, error) {
+	return fmt.Sprintf("neo4j+s://%s-%s.databases.neo4j.io", dbid, a.environment), nil
+}
+
+func (a *AuraAddressStrategy) String() string {
+	return fmt.Sprintf("Aura environment '%s'", a.environment)
+}
+
+type TemplateAddressStrategy struct {
+	template string
+}
+
+func NewTemplateAddressStrategy(template string) (*TemplateAddressStrategy, error) {
+	if err := validateURI(strings.ReplaceAll(template, "{dbid}", "dbid")); err != nil {
+		return nil, err
+	}
+	return &TemplateAddressStrategy{template}, nil
+}
+
+func (t *TemplateAddressStrategy) Address(dbid string) (string, error) {
+	return strings.ReplaceAll(t.template, "{dbid}", dbid), nil
+}
+
+func (t *TemplateAddressStrategy) String() string {
+	return fmt.Sprintf("template '%s'", t.template)
+}
+
+// Used when neither an environment nor a template is configured, so every job needs an explicit URI
+type ExplicitAddressStrategy struct {
+}
+
+func (e *ExplicitAddressStrategy) Address(dbid string) (string, error) {
+	return "", newWorkloadError(ErrInvalid, "No address configured for database '%s', an explicit uri is required", dbid)
+}
+
+func (e *ExplicitAddressStrategy) String() string {
+	return "explicit URIs only"
+}
+
+func validateURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return newWorkloadError(ErrInvalid, "Invalid uri '%s': %v", uri, err)
+	}
+	supported := false
+	for _, scheme := range supportedSchemes {
+		if parsed.Scheme == scheme {
+			supported = true
+		}
+	}
+	if !supported {
+		return newWorkloadError(ErrInvalid, "Invalid uri '%s': scheme must be one of %s", uri, strings.Join(supportedSchemes, ", "))
+	}
+	if parsed.Hostname() == "" {
+		return newWorkloadError(ErrInvalid, "Invalid uri '%s': missing host", uri)
+	}
+	if parsed.User != nil {
+		// Credentials in the URI would end up in logs and job listings
+		return newWorkloadError(ErrInvalid, "Invalid uri for host '%s': credentials must not be part of the uri", parsed.Hostname())
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// The versioned REST API under /api/v1 exposes the same operations as the legacy GET routes, but with proper HTTP
+// methods, JSON request bodies and status codes. All errors are returned in a consistent envelope:
+//
+//   {"error":{"status":404,"message":"Failed to find job","detail":"Could not find client for database 'abc'"}}
+//
+// The legacy routes remain available and unchanged for existing scripts like latency-benchmark.sh.
+
+const apiPrefix = "/api/v1"
+
+// Configuration for a single Neo4j job, as sent in the body of POST /api/v1/jobs or listed in the configuration file
+type JobSpec struct {
+	Dbid        string `json:"dbid,omitempty" yaml:"dbid,omitempty"`
+	Alias       string `json:"alias,omitempty" yaml:"alias,omitempty"` // name of the job, defaults to the dbid or the host of the uri
+	URI         string `json:"uri,omitempty" yaml:"uri,omitempty"`     // explicit address, instead of the address strategy for the dbid
+	Database    string `json:"database,omitempty" yaml:"database,omitempty"`
+	Label       string `json:"label,omitempty" yaml:"label,omitempty"` // human readable name, for display only
+	Username    string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password    string `json:"password,omitempty" yaml:"password,omitempty"`
+	Credentials string `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+	Workload    string `json:"workload,omitempty" yaml:"workload,omitempty"` // name of the workload profile with the query rates
+}
+
+// The state of a single Neo4j job, as returned by the API
+type JobStatus struct {
+	Dbid        string `json:"dbid"`
+	Label       string `json:"label,omitempty"`
+	Address     string `json:"address"`
+	Database    string `json:"database"`
+	Credentials string `json:"credentials,omitempty"`
+	Workload    string `json:"workload,omitempty"`
+	Running     bool   `json:"running"`
+	Read        int    `json:"read"`
+	Write       int    `json:"write"`
+}
+
+// Configuration for the workload as a whole, as sent in the body of PUT /api/v1/workload
+type WorkloadSpec struct {
+	Running bool `json:"running"`
+}
+
+type WorkloadStatus struct {
+	Running bool        `json:"running"`
+	Jobs    []JobStatus `json:"jobs"`
+}
+
+type apiError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		s.writeAPIError(writer, http.StatusInternalServerError, "Failed to create result", err)
+		return
+	}
+	writer.Header().Set(contentType, contentTypeJSON)
+	writer.WriteHeader(status)
+	writer.Write(data)
+}
+
+func (s *Server) writeAPIError(writer http.ResponseWriter, status int, message string, err error) {
+	envelope := map[string]apiError{"error": {Status: status, Message: message}}
+	if err != nil {
+		envelope["error"] = apiError{status, message, err.Error()}
+	}
+	data, _ := json.Marshal(envelope)
+	writer.Header().Set(contentType, contentTypeJSON)
+	writer.WriteHeader(status)
+	writer.Write(data)
+}
+
+func (s *Server) handleAPIError(writer http.ResponseWriter, message string, err error) {
+	s.writeAPIError(writer, statusOf(err), message, err)
+}
+
+func (s *Server) methodNotAllowed(writer http.ResponseWriter, request *http.Request, allowed ...string) {
+	writer.Header().Set("Allow", strings.Join(allowed, ", "))
+	s.writeAPIError(writer, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s not allowed for %s", request.Method, request.URL.Path), nil)
+}
+
+func decodeBody(request *http.Request, value interface{}) error {
+	if request.Body == nil {
+		return newWorkloadError(ErrInvalid, "Missing JSON request body")
+	}
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return newWorkloadError(ErrInvalid, "Invalid JSON request body: %v", err)
+	}
+	return nil
+}
+
+func makeJobStatus(client *Neo4jJob, workload *Workload) JobStatus {
+	read, _ := workload.CountsFor(client.dbid, "read")
+	write, _ := workload.CountsFor(client.dbid, "write")
+	return JobStatus{client.dbid, client.label, client.neo4j.neo4jAddress, client.neo4j.database, client.neo4j.credentials, client.workload, client.isRunning(), read, write}
+}
+
+func makeJobStatuses(clients []*Neo4jJob, workload *Workload) []JobStatus {
+	statuses := []JobStatus{}
+	for _, client := range clients {
+		statuses = append(statuses, makeJobStatus(client, workload))
+	}
+	return statuses
+}
+
+// Basic authentication carries the Neo4j credentials, unless it was used to authenticate with the server itself
+func (s *Server) basicAuthIsNeo4jCredentials(request *http.Request) bool {
+	_, legacy := s.authenticator.(*BasicAuthPresenceAuthenticator)
+	return request != nil && (legacy || tokenOf(request) != "")
+}
+
+// The address of the job is the explicit uri of the spec, or otherwise made from the dbid by the address strategy
+func (s *Server) addressFor(spec JobSpec) (string, error) {
+	if spec.URI != "" {
+		return spec.URI, validateURI(spec.URI)
+	}
+	if spec.Dbid == "" {
+		return "", newWorkloadError(ErrInvalid, "Either a database id or a uri is required")
+	}
+	return s.makeAddress(spec.Dbid)
+}
+
+// The name of the job is the alias, the dbid or the host of the uri, in that order
+func nameFor(spec JobSpec) string {
+	switch {
+	case spec.Alias != "":
+		return spec.Alias
+	case spec.Dbid != "":
+		return spec.Dbid
+	case spec.URI != "":
+		if parsed, err := url.Parse(spec.URI); err == nil {
+			return parsed.Hostname()
+		}
+	}
+	return ""
+}
+
+// Neo4j database names are 3 to 63 characters, starting with a letter, and containing only letters, digits, dots and dashes
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9.\-]{2,62}$`)
+
+// The database can be part of the dbid as '<dbid>@<database>', or given separately, and defaults to 'neo4j'
+func databaseFor(spec JobSpec) (JobSpec, error) {
+	if at := strings.LastIndex(spec.Dbid, "@"); at >= 0 && spec.Database == "" {
+		spec.Dbid, spec.Database = spec.Dbid[:at], spec.Dbid[at+1:]
+	}
+	if spec.Database == "" {
+		spec.Database = defaultDatabase
+	}
+	if !databaseNamePattern.MatchString(spec.Database) {
+		return spec, newWorkloadError(ErrInvalid, "Invalid database name: '%s'", spec.Database)
+	}
+	return spec, nil
+}
+
+// Jobs for other databases than the default have the database in their name, so that several databases on the same
+// DBMS can be benchmarked as separate jobs, and are distinguished in all results
+func jobName(name string, database string) string {
+	if database == defaultDatabase {
+		return name
+	}
+	return fmt.Sprintf("%s@%s", name, database)
+}
+
+// Create a job from the spec. The Neo4j credentials are, in order of preference: named credentials from the spec,
+// username and password from the spec, credentials in the store named after the job, or basic authentication.
+func (s *Server) newNeo4jJob(spec JobSpec, request *http.Request) (*Neo4jJob, error) {
+	s.configLock.Lock()
+	profiles := s.config.profiles()
+	s.configLock.Unlock()
+	return s.newNeo4jJobWith(profiles, spec, request)
+}
+
+// Create a job from the spec with one of the given workload profiles
+func (s *Server) newNeo4jJobWith(profiles map[string]WorkloadProfile, spec JobSpec, request *http.Request) (*Neo4jJob, error) {
+	spec, err := databaseFor(spec)
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := profiles[spec.Workload]
+	if spec.Workload == "" {
+		profile, ok = profiles[defaultWorkload]
+	}
+	if !ok {
+		return nil, newWorkloadError(ErrInvalid, "Unknown workload '%s'", spec.Workload)
+	}
+	address, err := s.addressFor(spec)
+	if err != nil {
+		return nil, err
+	}
+	name := nameFor(spec)
+	if name == "" || strings.ContainsAny(name, "/@") {
+		return nil, newWorkloadError(ErrInvalid, "Invalid database id: '%s'", name)
+	}
+	explicit := spec.Username != "" || spec.Password != ""
+	if spec.Credentials == "" && !explicit && s.credentials != nil && s.credentials.Has(name) {
+		spec.Credentials = name
+	}
+	if spec.Alias == "" {
+		name = jobName(name, spec.Database)
+	}
+	if spec.Credentials != "" {
+		if s.credentials == nil || !s.credentials.Has(spec.Credentials) {
+			return nil, newWorkloadError(ErrInvalid, "Unknown credentials '%s'", spec.Credentials)
+		}
+		job := NewNeo4jJob(*NewNeo4jWithCredentials(name, address, spec.Database, spec.Credentials))
+		job.label, job.workload, job.profile = spec.Label, spec.Workload, profile
+		return job, nil
+	}
+	if !explicit && s.basicAuthIsNeo4jCredentials(request) {
+		spec.Username, spec.Password, _ = request.BasicAuth()
+	}
+	job := NewNeo4jJob(*NewNeo4j(name, address, spec.Database, spec.Username, spec.Password))
+	job.label, job.workload, job.profile = spec.Label, spec.Workload, profile
+	return job, nil
+}
+
+func (s *Server) apiCredentialsHandler(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	names := []string{}
+	if s.credentials != nil {
+		names = s.credentials.Names()
+	}
+	s.writeJSON(writer, http.StatusOK, names)
+}
+
+func (s *Server) findJob(workload *Workload, dbid string) (*Neo4jJob, error) {
+	err, found := workload.Find(&Neo4jJob{dbid: dbid, neo4j: Neo4j{dbid: dbid}})
+	return found, err
+}
+
+func (s *Server) apiJobsHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, parts []string) {
+	switch len(parts) {
+	case 1:
+		switch request.Method {
+		case http.MethodGet:
+			s.writeJSON(writer, http.StatusOK, makeJobStatuses(workload.List(), workload))
+		case http.MethodPost:
+			var spec JobSpec
+			if err := decodeBody(request, &spec); err != nil {
+				s.handleAPIError(writer, "Failed to read job specification", err)
+				return
+			}
+			job, err := s.newNeo4jJob(spec, request)
+			if err == nil {
+				err = workload.Add(job)
+			}
+			if err != nil {
+				s.handleAPIError(writer, "Failed to add job", err)
+			} else {
+				writer.Header().Set("Location", fmt.Sprintf("%s/jobs/%s", apiPrefix, job.dbid))
+				s.writeJSON(writer, http.StatusCreated, makeJobStatus(job, workload))
+			}
+		default:
+			s.methodNotAllowed(writer, request, http.MethodGet, http.MethodPost)
+		}
+	case 2:
+		job, err := s.findJob(workload, parts[1])
+		if err != nil {
+			s.handleAPIError(writer, "Failed to find job", err)
+			return
+		}
+		switch request.Method {
+		case http.MethodGet:
+			s.writeJSON(writer, http.StatusOK, makeJobStatus(job, workload))
+		case http.MethodDelete:
+			if err := workload.Remove(job); err != nil {
+				s.handleAPIError(writer, "Failed to remove job", err)
+			} else {
+				writer.WriteHeader(http.StatusNoContent)
+			}
+		default:
+			s.methodNotAllowed(writer, request, http.MethodGet, http.MethodDelete)
+		}
+	case 3:
+		job, err := s.findJob(workload, parts[1])
+		if err != nil {
+			s.handleAPIError(writer, "Failed to find job", err)
+		} else if parts[2] == "topology" {
+			s.apiTopologyHandler(writer, request, workload, job)
+		} else if parts[2] == "leader-switches" {
+			s.apiLeaderSwitchesHandler(writer, request, workload, job)
+		} else if parts[2] == "members" {
+			s.apiMembersHandler(writer, request, workload, job)
+		} else if parts[2] == "lag" {
+			s.apiLagHandler(writer, request, workload, job)
+		} else if parts[2] == "causal" {
+			s.apiCausalHandler(writer, request, workload, job)
+		} else if parts[2] == "integrity" {
+			s.apiIntegrityHandler(writer, request, workload, job)
+		} else if parts[2] == "contention" {
+			s.apiContentionHandler(writer, request, workload, job)
+		} else {
+			s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+		}
+	default:
+		s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+	}
+}
+
+func (s *Server) apiWorkloadHandler(writer http.ResponseWriter, request *http.Request, workload *Workload) {
+	switch request.Method {
+	case http.MethodGet:
+		s.writeJSON(writer, http.StatusOK, WorkloadStatus{workload.running, makeJobStatuses(workload.List(), workload)})
+	case http.MethodPut:
+		var spec WorkloadSpec
+		if err := decodeBody(request, &spec); err != nil {
+			s.handleAPIError(writer, "Failed to read workload specification", err)
+			return
+		}
+		var err error
+		if spec.Running {
+			_, err = workload.Start()
+		} else {
+			_, err = workload.Stop()
+		}
+		if err != nil {
+			s.handleAPIError(writer, "Failed to update workload", err)
+		} else {
+			s.writeJSON(writer, http.StatusOK, WorkloadStatus{workload.running, makeJobStatuses(workload.List(), workload)})
+		}
+	default:
+		s.methodNotAllowed(writer, request, http.MethodGet, http.MethodPut)
+	}
+}
+
+func (s *Server) apiStatsHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, parts []string) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	var result *Neo4jResult
+	var err error
+	switch {
+	case len(parts) == 1:
+		result, err = workload.Results()
+	case len(parts) == 2 && parts[1] == "table":
+		result, err = workload.ResultsTable()
+	case len(parts) == 3:
+		if _, err = s.findJob(workload, parts[1]); err == nil {
+			result, err = workload.ResultsFor(parts[1], parts[2])
+		}
+	case len(parts) == 4 && parts[3] == "members":
+		if _, err = s.findJob(workload, parts[1]); err == nil {
+			result, err = workload.MemberSummary(parts[1], parts[2])
+		}
+	case len(parts) == 4 && parts[3] == "breakdown":
+		if _, err = s.findJob(workload, parts[1]); err == nil {
+			result, err = workload.BreakdownFor(parts[1], parts[2])
+		}
+	case len(parts) == 4 && parts[3] == "attempts":
+		if _, err = s.findJob(workload, parts[1]); err == nil {
+			result, err = workload.AttemptsFor(parts[1], parts[2])
+		}
+	default:
+		s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+		return
+	}
+	if err != nil {
+		s.handleAPIError(writer, "Failed to get results", err)
+	} else {
+		s.writeJSON(writer, http.StatusOK, result)
+	}
+}
+
+func (s *Server) apiHandler(workload *Workload) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(request.URL.Path, apiPrefix), "/"), "/")
+		switch parts[0] {
+		case "jobs":
+			s.apiJobsHandler(writer, request, workload, parts)
+		case "workload":
+			if len(parts) == 1 {
+				s.apiWorkloadHandler(writer, request, workload)
+			} else {
+				s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+			}
+		case "stats":
+			s.apiStatsHandler(writer, request, workload, parts)
+		case "import":
+			if len(parts) == 1 {
+				s.apiImportHandler(writer, request, workload)
+			} else {
+				s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+			}
+		case "reload":
+			if len(parts) == 1 {
+				s.apiReloadHandler(writer, request, workload)
+			} else {
+				s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+			}
+		case "discovery":
+			if len(parts) == 1 {
+				s.apiDiscoveryHandler(writer, request)
+			} else {
+				s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+			}
+		case "credentials":
+			if len(parts) == 1 {
+				s.apiCredentialsHandler(writer, request)
+			} else {
+				s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+			}
+		default:
+			s.writeAPIError(writer, http.StatusNotFound, fmt.Sprintf("Invalid path: %s", request.URL.Path), nil)
+		}
+	}
+}
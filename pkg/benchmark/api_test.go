@@ -0,0 +1,108 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Like assertWildcardMatches, but all other characters of the expected text match literally, including those in URIs
+// like 'neo4j+s://'
+func assertQuotedWildcardMatches(t *testing.T, expected string, actual []byte) {
+	wildcards := regexp.MustCompile(`(\*{3}|\*\?[<>=\d]*\*)`)
+	pattern := ""
+	ops := []string{}
+	prev := 0
+	for _, m := range wildcards.FindAllStringIndex(expected, -1) {
+		pattern += regexp.QuoteMeta(expected[prev:m[0]])
+		expr := expected[m[0]:m[1]]
+		if expr == `***` || expr == `*?*` {
+			pattern += `.+`
+		} else {
+			pattern += `(\d+)`
+			ops = append(ops, expr[2:len(expr)-1])
+		}
+		prev = m[1]
+	}
+	pattern += regexp.QuoteMeta(expected[prev:])
+	submatches := regexp.MustCompile(pattern).FindSubmatch(actual)
+	if submatches == nil {
+		t.Errorf("Failed to match '%s' to '%s'", expected, actual)
+		return
+	}
+	for i, op := range ops {
+		evalSubmatch(t, string(submatches[i+1]), op)
+	}
+}
+
+func Test_API(t *testing.T) {
+	s, workload := mockServer(t)
+	handler := s.apiHandler(workload)
+
+	tests := []struct {
+		method     string
+		path       string
+		body       string
+		statuscode int
+		expected   string
+	}{
+		{method: "GET", path: "/api/v1/jobs", statuscode: http.StatusOK, expected: `[]`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":"abc","username":"neo4j","password":"secret"}`, statuscode: http.StatusCreated, expected: `{"dbid":"abc","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":"abc"}`, statuscode: http.StatusConflict, expected: `{"error":{"status":409,"message":"Failed to add job","detail":"Client for database 'abc' already exists"}}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":""}`, statuscode: http.StatusBadRequest, expected: `{"error":{"status":400,"message":"Failed to add job","detail":"Either a database id or a uri is required"}}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"id":"xyz"}`, statuscode: http.StatusBadRequest, expected: `{"error":{"status":400,"message":"Failed to read job specification","detail":"Invalid JSON request body: json: unknown field \"id\""}}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":"xyz"}`, statuscode: http.StatusCreated, expected: `{"dbid":"xyz","address":"neo4j+s://xyz-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0}`},
+		{method: "GET", path: "/api/v1/jobs", statuscode: http.StatusOK, expected: `[{"dbid":"abc","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0},{"dbid":"xyz","address":"neo4j+s://xyz-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0}]`},
+		{method: "GET", path: "/api/v1/jobs/xyz", statuscode: http.StatusOK, expected: `{"dbid":"xyz","address":"neo4j+s://xyz-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0}`},
+		{method: "GET", path: "/api/v1/jobs/123", statuscode: http.StatusNotFound, expected: `{"error":{"status":404,"message":"Failed to find job","detail":"Could not find client for database '123'"}}`},
+		{method: "PUT", path: "/api/v1/jobs/xyz", statuscode: http.StatusMethodNotAllowed, expected: `{"error":{"status":405,"message":"Method PUT not allowed for /api/v1/jobs/xyz"}}`},
+		{method: "DELETE", path: "/api/v1/jobs/xyz", statuscode: http.StatusNoContent, expected: ``},
+		{method: "DELETE", path: "/api/v1/jobs/xyz", statuscode: http.StatusNotFound, expected: `{"error":{"status":404,"message":"Failed to find job","detail":"Could not find client for database 'xyz'"}}`},
+		{method: "GET", path: "/api/v1/workload", statuscode: http.StatusOK, expected: `{"running":false,"jobs":[{"dbid":"abc","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0}]}`},
+		{method: "PUT", path: "/api/v1/workload", body: `{"running":false}`, statuscode: http.StatusConflict, expected: `{"error":{"status":409,"message":"Failed to update workload","detail":"Already stopped"}}`},
+		{method: "PUT", path: "/api/v1/workload", body: `{"running":true}`, statuscode: http.StatusOK, expected: `{"running":true,"jobs":[{"dbid":"abc","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"neo4j","running":true,"read":*?>=0*,"write":*?>=0*}]}`},
+		{method: "PUT", path: "/api/v1/workload", body: `{"running":true}`, statuscode: http.StatusConflict, expected: `{"error":{"status":409,"message":"Failed to update workload","detail":"Already started"}}`},
+		{method: "PUT", path: "/api/v1/workload", body: `{"running":false}`, statuscode: http.StatusOK, expected: `{"running":false,"jobs":[{"dbid":"abc","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"neo4j","running":***,"read":*?>=0*,"write":*?>=0*}]}`},
+		{method: "GET", path: "/api/v1/stats", statuscode: http.StatusOK, expected: `{"Header":["dbid","verb","count"],"Rows":[["abc","read",*?>=0*],["abc","write",*?>=0*]]}`},
+		{method: "GET", path: "/api/v1/stats/abc/other", statuscode: http.StatusBadRequest, expected: `{"error":{"status":400,"message":"Failed to get results","detail":"Invalid result verb: other"}}`},
+		{method: "GET", path: "/api/v1/stats/xyz/read", statuscode: http.StatusNotFound, expected: `{"error":{"status":404,"message":"Failed to get results","detail":"Could not find client for database 'xyz'"}}`},
+		{method: "POST", path: "/api/v1/stats", statuscode: http.StatusMethodNotAllowed, expected: `{"error":{"status":405,"message":"Method POST not allowed for /api/v1/stats"}}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"uri":"bolt://localhost:7687"}`, statuscode: http.StatusCreated, expected: `{"dbid":"localhost","address":"bolt://localhost:7687","database":"neo4j","running":false,"read":0,"write":0}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"alias":"local","uri":"neo4j://localhost"}`, statuscode: http.StatusCreated, expected: `{"dbid":"local","address":"neo4j://localhost","database":"neo4j","running":false,"read":0,"write":0}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"alias":"xyz","dbid":"abc"}`, statuscode: http.StatusCreated, expected: `{"dbid":"xyz","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"neo4j","running":false,"read":0,"write":0}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"uri":"http://localhost"}`, statuscode: http.StatusBadRequest, expected: `{"error":{"status":400,"message":"Failed to add job","detail":"Invalid uri 'http://localhost': scheme must be one of neo4j, neo4j+s, neo4j+ssc, bolt, bolt+s, bolt+ssc"}}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":"abc","database":"movies"}`, statuscode: http.StatusCreated, expected: `{"dbid":"abc@movies","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"movies","running":false,"read":0,"write":0}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":"abc@movies"}`, statuscode: http.StatusConflict, expected: `{"error":{"status":409,"message":"Failed to add job","detail":"Client for database 'abc@movies' already exists"}}`},
+		{method: "POST", path: "/api/v1/jobs", body: `{"dbid":"abc","database":"1x"}`, statuscode: http.StatusBadRequest, expected: `{"error":{"status":400,"message":"Failed to add job","detail":"Invalid database name: '1x'"}}`},
+		{method: "GET", path: "/api/v1/jobs/abc@movies", statuscode: http.StatusOK, expected: `{"dbid":"abc@movies","address":"neo4j+s://abc-testenv.databases.neo4j.io","database":"movies","running":false,"read":0,"write":0}`},
+		{method: "DELETE", path: "/api/v1/jobs/abc@movies", statuscode: http.StatusNoContent, expected: ``},
+		{method: "DELETE", path: "/api/v1/jobs/localhost", statuscode: http.StatusNoContent, expected: ``},
+		{method: "DELETE", path: "/api/v1/jobs/local", statuscode: http.StatusNoContent, expected: ``},
+		{method: "DELETE", path: "/api/v1/jobs/xyz", statuscode: http.StatusNoContent, expected: ``},
+		{method: "GET", path: "/api/v1/credentials", statuscode: http.StatusOK, expected: `[]`},
+		{method: "GET", path: "/api/v1/other", statuscode: http.StatusNotFound, expected: `{"error":{"status":404,"message":"Invalid path: /api/v1/other"}}`},
+	}
+
+	for index, data := range tests {
+		testName := fmt.Sprintf("test_%d %s %s", index+1, data.method, data.path)
+		t.Run(testName, func(t *testing.T) {
+			request := httptest.NewRequest(data.method, data.path, strings.NewReader(data.body))
+			request.SetBasicAuth("ignored", "secret")
+			responseRecorder := httptest.NewRecorder()
+			handler(responseRecorder, request)
+			response := responseRecorder.Result()
+			body, _ := ioutil.ReadAll(response.Body)
+			if strings.Contains(data.expected, "*") {
+				assertQuotedWildcardMatches(t, data.expected, body)
+			} else {
+				assert.Equal(t, data.expected, string(body))
+			}
+			assert.Equal(t, data.statuscode, response.StatusCode)
+		})
+	}
+}
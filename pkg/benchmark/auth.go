@@ -0,0 +1,250 @@
+package benchmark
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Authentication of requests to the benchmark service is separate from the Neo4j credentials used to run queries.
+// When AUTH_FILE is set, it names an htpasswd-style file with one user or API token per line:
+//
+//     # name:digest:role[:kind]
+//     alice:{SHA256}5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8:operator
+//     grafana:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=:viewer:token
+//
+// The digest is of the password or token, either '{SHA256}' followed by the hex encoded SHA-256, or '{SHA}' followed
+// by the base64 encoded SHA-1 as produced by 'htpasswd -s'. The kind is 'password' unless it is given as 'token'.
+// Clients authenticate with a token in 'Authorization: Bearer <token>' or 'X-Api-Token: <token>', or with the name
+// and password of a user with basic authentication, but never with a password as a token or the other way round. The
+// X-Api-Token header allows the basic authentication header to continue carrying the Neo4j credentials on the legacy
+// /neo4j/add route.
+//
+// Without AUTH_FILE the service behaves as it always has: any basic authentication header is accepted as an operator.
+
+type Role int
+
+const (
+	RoleAnonymous Role = iota // may see the help and API specification
+	RoleViewer                // may also view jobs, stats, charts and reports
+	RoleOperator              // may also add and remove jobs, and start and stop the workload
+)
+
+const apiTokenHeader = "X-Api-Token"
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+func (r Role) String() string {
+	switch r {
+	case RoleAnonymous:
+		return "anonymous"
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	default:
+		return "INVALID"
+	}
+}
+
+func parseRole(name string) (Role, error) {
+	switch name {
+	case "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	default:
+		return RoleAnonymous, errors.New(fmt.Sprintf("Invalid role '%s', expected 'viewer' or 'operator'", name))
+	}
+}
+
+type Principal struct {
+	Name string
+	Role Role
+}
+
+type Authenticator interface {
+	Authenticate(request *http.Request) (*Principal, error)
+}
+
+// Accepts any request with basic authentication, which was the behaviour before real authentication was added
+type BasicAuthPresenceAuthenticator struct {
+}
+
+func (a *BasicAuthPresenceAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	username, _, ok := request.BasicAuth()
+	if !ok {
+		return nil, newWorkloadError(ErrUnauthorized, "No basic authentication information provided")
+	}
+	return &Principal{username, RoleOperator}, nil
+}
+
+type credentialDigest struct {
+	name   string
+	digest string
+	role   Role
+	token  bool // an API token rather than the password of a user
+}
+
+func (c credentialDigest) matches(secret string) bool {
+	var actual, expected string
+	switch {
+	case strings.HasPrefix(c.digest, "{SHA256}"):
+		// hex digests may be written in either case
+		sum := sha256.Sum256([]byte(secret))
+		actual = hex.EncodeToString(sum[:])
+		expected = strings.ToLower(strings.TrimPrefix(c.digest, "{SHA256}"))
+	case strings.HasPrefix(c.digest, "{SHA}"):
+		sum := sha1.Sum([]byte(secret))
+		actual = base64.StdEncoding.EncodeToString(sum[:])
+		expected = strings.TrimPrefix(c.digest, "{SHA}")
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) == 1
+}
+
+type TokenAuthenticator struct {
+	credentials []credentialDigest
+}
+
+func ParseTokenFile(reader io.Reader) (*TokenAuthenticator, error) {
+	authenticator := &TokenAuthenticator{[]credentialDigest{}}
+	scanner := bufio.NewScanner(reader)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ":")
+		if len(fields) != 3 && len(fields) != 4 {
+			return nil, errors.New(fmt.Sprintf("Invalid authentication entry on line %d: expected 'name:digest:role[:kind]'", line))
+		}
+		if !strings.HasPrefix(fields[1], "{SHA256}") && !strings.HasPrefix(fields[1], "{SHA}") {
+			return nil, errors.New(fmt.Sprintf("Invalid authentication entry on line %d: digest must start with {SHA256} or {SHA}", line))
+		}
+		role, err := parseRole(fields[2])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid authentication entry on line %d: %v", line, err))
+		}
+		token := false
+		if len(fields) == 4 {
+			switch fields[3] {
+			case "password":
+			case "token":
+				token = true
+			default:
+				return nil, errors.New(fmt.Sprintf("Invalid authentication entry on line %d: Invalid kind '%s', expected 'password' or 'token'", line, fields[3]))
+			}
+		}
+		authenticator.credentials = append(authenticator.credentials, credentialDigest{name: fields[0], digest: fields[1], role: role, token: token})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+func LoadTokenFile(path string) (*TokenAuthenticator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseTokenFile(file)
+}
+
+func tokenOf(request *http.Request) string {
+	if token := request.Header.Get(apiTokenHeader); token != "" {
+		return token
+	}
+	authorization := request.Header.Get("Authorization")
+	if strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	}
+	return ""
+}
+
+func (a *TokenAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	if token := tokenOf(request); token != "" {
+		for _, credential := range a.credentials {
+			if credential.token && credential.matches(token) {
+				return &Principal{credential.name, credential.role}, nil
+			}
+		}
+		return nil, newWorkloadError(ErrUnauthorized, "Invalid API token")
+	}
+	if username, password, ok := request.BasicAuth(); ok {
+		for _, credential := range a.credentials {
+			if !credential.token && credential.name == username && credential.matches(password) {
+				return &Principal{credential.name, credential.role}, nil
+			}
+		}
+		return nil, newWorkloadError(ErrUnauthorized, "Invalid username or password")
+	}
+	return nil, newWorkloadError(ErrUnauthorized, "No authentication information provided")
+}
+
+// The role required for a request, based on the method and path, so that viewers can see but not change anything
+func requiredRole(request *http.Request) Role {
+	path := request.URL.Path
+	switch {
+	case path == "/" || path == "/openapi.json" || path == "/healthz" || path == "/readyz":
+		return RoleAnonymous
+	case strings.HasPrefix(path, apiPrefix+"/"):
+		if request.Method == http.MethodGet || request.Method == http.MethodHead {
+			return RoleViewer
+		}
+		return RoleOperator
+	case strings.HasPrefix(path, "/neo4j/add/"), strings.HasPrefix(path, "/neo4j/remove/"), path == "/start", path == "/stop":
+		return RoleOperator
+	default:
+		return RoleViewer
+	}
+}
+
+func (s *Server) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		role := requiredRole(request)
+		if role == RoleAnonymous {
+			handler(writer, request)
+			return
+		}
+		api := strings.HasPrefix(request.URL.Path, apiPrefix+"/")
+		principal, err := s.authenticator.Authenticate(request)
+		if err != nil {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="latency-benchmark"`)
+			if api {
+				s.writeAPIError(writer, http.StatusUnauthorized, err.Error(), nil)
+			} else {
+				s.writeErrorStatus(writer, http.StatusUnauthorized, err.Error(), nil)
+			}
+			return
+		}
+		if principal.Role < role {
+			message := fmt.Sprintf("User '%s' with role '%s' requires role '%s'", principal.Name, principal.Role, role)
+			if api {
+				s.writeAPIError(writer, http.StatusForbidden, message, nil)
+			} else {
+				s.writeErrorStatus(writer, http.StatusForbidden, message, nil)
+			}
+			return
+		}
+		if role == RoleOperator {
+			log.Printf("User '%s' requested %s %s", principal.Name, request.Method, request.URL.Path)
+		}
+		handler(writer, request)
+	}
+}
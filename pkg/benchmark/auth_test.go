@@ -0,0 +1,121 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testTokenFile = `
+# name:digest:role
+alice:{SHA256}2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b:operator
+grafana:{SHA}R6ZSlky4Gw8rwyYvl/ilh97ZOCw=:viewer:password
+ci:{SHA256}2b2577f455616d63bbcb76676b219056860f972a5c38cc4fc33bec3f23e20561:operator:token
+dashboard:{SHA256}5E17BB983B1FF3B4ACBCAA8451A4173FAA5575C682E7CF8615E2ED5E50FAD7BD:viewer:token
+`
+
+func Test_ParseTokenFile(t *testing.T) {
+	tokens, err := ParseTokenFile(strings.NewReader(testTokenFile))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(tokens.credentials))
+
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{content: "alice:secret", expected: "Invalid authentication entry on line 1: expected 'name:digest:role[:kind]'"},
+		{content: "\nalice:secret:viewer", expected: "Invalid authentication entry on line 2: digest must start with {SHA256} or {SHA}"},
+		{content: "alice:{SHA}abc:admin", expected: "Invalid authentication entry on line 1: Invalid role 'admin', expected 'viewer' or 'operator'"},
+		{content: "alice:{SHA}abc:viewer:key", expected: "Invalid authentication entry on line 1: Invalid kind 'key', expected 'password' or 'token'"},
+	}
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d", index+1), func(t *testing.T) {
+			_, err := ParseTokenFile(strings.NewReader(data.content))
+			assert.NotNil(t, err)
+			assert.Equal(t, data.expected, err.Error())
+		})
+	}
+}
+
+func Test_Authorize(t *testing.T) {
+	s, _ := mockServer(t)
+	tokens, err := ParseTokenFile(strings.NewReader(testTokenFile))
+	assert.Nil(t, err)
+	s.authenticator = tokens
+	handler := s.authorize(func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "ok")
+	})
+
+	tests := []struct {
+		method     string
+		path       string
+		headers    map[string]string
+		basic      []string
+		statuscode int
+		expected   string
+	}{
+		{method: "GET", path: "/", statuscode: http.StatusOK, expected: `ok`},
+		{method: "GET", path: "/openapi.json", statuscode: http.StatusOK, expected: `ok`},
+		{method: "GET", path: "/stats", statuscode: http.StatusUnauthorized, expected: `{"message":"No authentication information provided"}`},
+		{method: "GET", path: "/stats", basic: []string{"alice", "wrong"}, statuscode: http.StatusUnauthorized, expected: `{"message":"Invalid username or password"}`},
+		{method: "GET", path: "/stats", basic: []string{"alice", "secret"}, statuscode: http.StatusOK, expected: `ok`},
+		{method: "GET", path: "/start", basic: []string{"alice", "secret"}, statuscode: http.StatusOK, expected: `ok`},
+		{method: "GET", path: "/stats", basic: []string{"grafana", "viewing"}, statuscode: http.StatusOK, expected: `ok`},
+		{method: "GET", path: "/neo4j/list", basic: []string{"grafana", "viewing"}, statuscode: http.StatusOK, expected: `ok`},
+		{method: "GET", path: "/start", basic: []string{"grafana", "viewing"}, statuscode: http.StatusForbidden, expected: `{"message":"User 'grafana' with role 'viewer' requires role 'operator'"}`},
+		{method: "GET", path: "/neo4j/remove/abc", basic: []string{"grafana", "viewing"}, statuscode: http.StatusForbidden, expected: `{"message":"User 'grafana' with role 'viewer' requires role 'operator'"}`},
+		{method: "GET", path: "/api/v1/jobs", headers: map[string]string{"Authorization": "Bearer view-token"}, statuscode: http.StatusOK, expected: `ok`},
+		{method: "POST", path: "/api/v1/jobs", headers: map[string]string{"Authorization": "Bearer view-token"}, statuscode: http.StatusForbidden, expected: `{"error":{"status":403,"message":"User 'dashboard' with role 'viewer' requires role 'operator'"}}`},
+		{method: "GET", path: "/api/v1/jobs", headers: map[string]string{"Authorization": "Bearer viewing"}, statuscode: http.StatusUnauthorized, expected: `{"error":{"status":401,"message":"Invalid API token"}}`},
+		{method: "GET", path: "/stats", basic: []string{"ci", "op-token"}, statuscode: http.StatusUnauthorized, expected: `{"message":"Invalid username or password"}`},
+		{method: "DELETE", path: "/api/v1/jobs/abc", headers: map[string]string{"Authorization": "Bearer op-token"}, statuscode: http.StatusOK, expected: `ok`},
+		{method: "DELETE", path: "/api/v1/jobs/abc", headers: map[string]string{"Authorization": "Bearer wrong"}, statuscode: http.StatusUnauthorized, expected: `{"error":{"status":401,"message":"Invalid API token"}}`},
+		{method: "GET", path: "/neo4j/add/abc", headers: map[string]string{apiTokenHeader: "op-token"}, basic: []string{"neo4j", "password"}, statuscode: http.StatusOK, expected: `ok`},
+	}
+
+	for index, data := range tests {
+		testName := fmt.Sprintf("test_%d %s %s", index+1, data.method, data.path)
+		t.Run(testName, func(t *testing.T) {
+			request := httptest.NewRequest(data.method, data.path, nil)
+			for key, value := range data.headers {
+				request.Header.Set(key, value)
+			}
+			if len(data.basic) == 2 {
+				request.SetBasicAuth(data.basic[0], data.basic[1])
+			}
+			responseRecorder := httptest.NewRecorder()
+			handler(responseRecorder, request)
+			response := responseRecorder.Result()
+			body, _ := ioutil.ReadAll(response.Body)
+			assert.Equal(t, data.expected, string(body))
+			assert.Equal(t, data.statuscode, response.StatusCode)
+		})
+	}
+}
+
+func Test_BasicAuthIsNeo4jCredentials(t *testing.T) {
+	s, _ := mockServer(t)
+	request := httptest.NewRequest("GET", "/neo4j/add/abc", nil)
+	request.SetBasicAuth("neo4j", "password")
+	assert.True(t, s.basicAuthIsNeo4jCredentials(request), "Legacy authentication does not use basic authentication")
+
+	tokens, err := ParseTokenFile(strings.NewReader(testTokenFile))
+	assert.Nil(t, err)
+	s.authenticator = tokens
+	assert.False(t, s.basicAuthIsNeo4jCredentials(request), "Basic authentication was used to authenticate with the server")
+	request.Header.Set(apiTokenHeader, "op-token")
+	assert.True(t, s.basicAuthIsNeo4jCredentials(request), "API token was used to authenticate with the server")
+}
+
+func Test_CredentialDigestCase(t *testing.T) {
+	sha256 := credentialDigest{name: "alice", digest: "{SHA256}2BB80D537B1DA3E38BD30361AA855686BDE0EACD7162FEF6A25FE97BF527A25B"}
+	assert.True(t, sha256.matches("secret"), "Hex digests may be upper case")
+	sha1 := credentialDigest{name: "grafana", digest: "{SHA}R6ZSlky4Gw8rwyYvl/ilh97ZOCw="}
+	assert.True(t, sha1.matches("viewing"))
+	sha1.digest = "{SHA}r6zSlky4Gw8rwyYvl/ilh97ZOCw="
+	assert.False(t, sha1.matches("viewing"), "Base64 digests are case sensitive")
+}
@@ -0,0 +1,115 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Jobs with a causal rate run pairs of queries: a write that increments the value of a ClientBenchmarkCausal node, and
+// a read in a new session that starts after the bookmark of the write, so the member serving it has to wait until it
+// has caught up with the write. These reads are recorded as 'causal' results, to compare their latency with the plain
+// reads of the job, which wait for nothing. A read that does not see the value it just wrote is a stale read, which
+// bookmarks should make impossible, and is recorded as a 'stale-read:error' event.
+
+const staleReadError = "stale-read:error"
+
+func singleInt(result *Neo4jResult) (int64, error) {
+	if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+		return 0, errors.New(fmt.Sprintf("expected 1 row with 1 column but got %d rows", len(result.Rows)))
+	}
+	value, ok := result.Rows[0][0].(int64)
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("expected an integer but got %v", result.Rows[0][0]))
+	}
+	return value, nil
+}
+
+// Write a new value, read it back after the bookmark of the write and send the duration of the read to the channel
+func (n *Neo4jJob) causalRead(ch chan Message, runner QuerySession) error {
+	result, bookmark, err := runner.RunCausalQuery(neo4j.AccessModeWrite, "MERGE (c:ClientBenchmarkCausal) ON CREATE SET c.value = 0 SET c.value = c.value + 1 RETURN c.value", "")
+	if err != nil {
+		return err
+	}
+	written, err := singleInt(result)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	result, _, err = runner.RunCausalQuery(neo4j.AccessModeRead, "MATCH (c:ClientBenchmarkCausal) RETURN c.value", bookmark)
+	if err != nil {
+		return err
+	}
+	duration := time.Since(started)
+	seen, err := singleInt(result)
+	if err != nil {
+		return err
+	}
+	if seen < written {
+		log.Printf("Stale read of '%s' from %s: wrote %d but read %d", n.dbid, result.server.Address, written, seen)
+		ch <- Message{verb: staleReadError, dbid: n.dbid, value: -1, message: fmt.Sprintf("wrote %d but read %d from %s", written, seen, result.server.Address), server: result.server}
+		return nil
+	}
+	ch <- Message{verb: "causal", dbid: n.dbid, value: duration.Milliseconds(), server: result.server, timing: result.timing.withTotal(duration)}
+	return nil
+}
+
+func (n *Neo4jJob) runCausalWorkload(ch chan Message, maker SessionMaker, interval time.Duration) {
+	runner, err := maker.NewQuerySession(n.neo4j, neo4j.AccessModeWrite)
+	if err != nil {
+		log.Printf("Failed to create runner for causal workload against '%s': %v", n.dbid, err)
+		ch <- Message{verb: "causal:error", dbid: n.dbid, value: -1, message: err.Error()}
+		return
+	}
+	defer runner.Close()
+	log.Printf("Starting causal workload against '%s' every %v", n.dbid, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for n.tick(ticker) {
+		if err := n.causalRead(ch, runner); err != nil {
+			log.Printf("Error running causal read against '%s': %v", n.dbid, err)
+			ch <- Message{verb: "causal:error", dbid: n.dbid, value: -1, message: err.Error()}
+		}
+	}
+	log.Printf("Finishing causal workload against '%s'", n.dbid)
+}
+
+// The latencies of the causally consistent reads of each job with any, compared with its plain reads, for one job or
+// for all jobs if dbid is empty
+func (w *Workload) CausalSummary(dbid string) *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "reads", "causal", "stale", "errors", "read p50", "causal p50", "extra p50", "read p99", "causal p99", "extra p99"})
+	counts := map[string]int{}
+	for _, event := range w.Events() {
+		counts[fmt.Sprintf("%s:%s", event.kind, event.dbid)]++
+	}
+	for _, client := range w.List() {
+		if dbid != "" && client.dbid != dbid {
+			continue
+		}
+		causal := w.results.For(client.dbid, "causal").durations
+		stale := counts[fmt.Sprintf("%s:%s", staleReadError, client.dbid)]
+		failed := counts[fmt.Sprintf("causal:error:%s", client.dbid)]
+		if len(causal) == 0 && stale == 0 && failed == 0 {
+			continue
+		}
+		reads := w.results.For(client.dbid, "read").durations
+		row := []interface{}{client.dbid, len(reads), len(causal), stale, failed}
+		for _, p := range []float64{50, 99} {
+			plain, consistent := percentile(reads, p), percentile(causal, p)
+			row = append(row, plain, consistent, consistent-plain)
+		}
+		result.add(row)
+	}
+	return result
+}
+
+func (s *Server) apiCausalHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, workload.CausalSummary(job.dbid))
+}
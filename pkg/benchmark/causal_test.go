@@ -0,0 +1,75 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Keeps one value, which reads only see after its write if they pass the bookmark of the write, unless stale is set
+type TestCausalSession struct {
+	TestQuerySession
+	value     int64
+	stale     bool
+	bookmarks []string
+}
+
+func (r *TestCausalSession) RunCausalQuery(accessMode neo4j.AccessMode, query string, bookmark string) (*Neo4jResult, string, error) {
+	r.bookmarks = append(r.bookmarks, bookmark)
+	result := NewNeo4jResult([]string{"c.value"})
+	result.server = Member{"core-2:7687", "Neo4j/4.2.0"}
+	if strings.HasPrefix(query, "MERGE") {
+		r.value++
+		result.add([]interface{}{r.value})
+		return result, fmt.Sprintf("FB:%d", r.value), nil
+	}
+	if bookmark == "" || r.stale {
+		result.add([]interface{}{r.value - 1})
+	} else {
+		result.add([]interface{}{r.value})
+	}
+	return result, bookmark, nil
+}
+
+func Test_CausalRead(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := mockPublishedWorkload(t)
+	job, err := s.findJob(workload, "abc")
+	assert.Nil(t, err)
+	runner := &TestCausalSession{}
+
+	ch := make(chan Message, 10)
+	assert.Nil(t, job.causalRead(ch, runner))
+	assert.Nil(t, job.causalRead(ch, runner))
+	runner.stale = true
+	assert.Nil(t, job.causalRead(ch, runner))
+	close(ch)
+	assert.Equal(t, []string{"", "FB:1", "", "FB:2", "", "FB:3"}, runner.bookmarks)
+	kinds := []string{}
+	for msg := range ch {
+		kinds = append(kinds, msg.verb)
+		workload.record(msg)
+	}
+	assert.Equal(t, []string{"causal", "causal", staleReadError}, kinds)
+
+	summary := workload.CausalSummary("")
+	assert.Equal(t, []string{"dbid", "reads", "causal", "stale", "errors", "read p50", "causal p50", "extra p50", "read p99", "causal p99", "extra p99"}, summary.Header)
+	assert.Equal(t, 1, len(summary.Rows))
+	assert.Equal(t, []interface{}{"abc", 10, 2, 1, 0, int64(14)}, summary.Rows[0][:6])
+
+	recorder := httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/xyz/causal", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"Header":["dbid","reads","causal","stale","errors","read p50","causal p50","extra p50","read p99","causal p99","extra p99"],"Rows":[]}`, recorder.Body.String())
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	var markdown strings.Builder
+	assert.Nil(t, report.WriteMarkdown(&markdown))
+	assert.Contains(t, markdown.String(), "## Causally consistent reads (ms)")
+	assert.Contains(t, markdown.String(), "| abc | "+staleReadError+" | 1 |")
+}
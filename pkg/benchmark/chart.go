@@ -0,0 +1,258 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+// Charts are rendered as plain SVG so that they can be embedded directly in reports, tickets and web pages without
+// any client-side javascript or external charting libraries. The data is usually a Neo4jResult in the same table
+// format as returned by the /stats endpoints, with the first column used as the x-axis (timestamp) and each of
+// the remaining columns becoming one line series (for example 'read:abc' and 'write:abc').
+
+const (
+	chartWidth        = 900
+	chartHeight       = 450
+	chartMarginLeft   = 70
+	chartMarginRight  = 180
+	chartMarginTop    = 40
+	chartMarginBottom = 50
+	chartTicks        = 5
+)
+
+var chartColors = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+type ChartSeries struct {
+	Name string
+	X    []int64
+	Y    []int64
+}
+
+// An annotation is drawn as a labelled vertical line, typically marking an event like a failover or an error
+type ChartAnnotation struct {
+	X     int64
+	Label string
+}
+
+type Chart struct {
+	Title       string
+	XLabel      string
+	YLabel      string
+	LogScale    bool
+	Series      []ChartSeries
+	Annotations []ChartAnnotation
+}
+
+func NewChart(title string, xLabel string, yLabel string, logScale bool) *Chart {
+	return &Chart{title, xLabel, yLabel, logScale, []ChartSeries{}, []ChartAnnotation{}}
+}
+
+func (c *Chart) Annotate(x int64, label string) {
+	c.Annotations = append(c.Annotations, ChartAnnotation{x, label})
+}
+
+// Convert a table result into a chart, using the first column as the x-axis and all other columns as series.
+// Cells that are not integers (or are zero on a log scale) are skipped rather than plotted.
+func NewChartFromResult(title string, yLabel string, logScale bool, result *Neo4jResult) (*Chart, error) {
+	if result == nil || len(result.Header) < 2 {
+		return nil, errors.New("Chart requires a result with at least two columns")
+	}
+	chart := NewChart(title, result.Header[0], yLabel, logScale)
+	for col := 1; col < len(result.Header); col++ {
+		series := ChartSeries{result.Header[col], []int64{}, []int64{}}
+		for _, row := range result.Rows {
+			x, ok := toInt64(row[0])
+			if !ok {
+				continue
+			}
+			y, ok := toInt64(row[col])
+			if !ok || (logScale && y <= 0) {
+				continue
+			}
+			series.X = append(series.X, x)
+			series.Y = append(series.Y, y)
+		}
+		chart.Series = append(chart.Series, series)
+	}
+	return chart, nil
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func (c *Chart) bounds() (int64, int64, int64, int64) {
+	minX, maxX := int64(math.MaxInt64), int64(math.MinInt64)
+	minY, maxY := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, series := range c.Series {
+		for i, x := range series.X {
+			y := series.Y[i]
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+			if y < minY {
+				minY = y
+			}
+			if y > maxY {
+				maxY = y
+			}
+		}
+	}
+	if minX > maxX {
+		if c.LogScale {
+			// without points, like when no query ran or all took 0 ms, a log scale still needs a decade to show
+			return 0, 1, 1, 10
+		}
+		return 0, 1, 0, 1
+	}
+	if minX == maxX {
+		maxX = minX + 1
+	}
+	if c.LogScale {
+		// Log scale charts are expanded to whole decades so that every tick falls inside the plot
+		minY, maxY = floorPow10(minY), ceilPow10(maxY)
+	} else if minY > 0 {
+		// Linear latency charts are easier to read when anchored at zero
+		minY = 0
+	}
+	if minY == maxY {
+		maxY = minY + 1
+	}
+	return minX, maxX, minY, maxY
+}
+
+func floorPow10(value int64) int64 {
+	power := int64(1)
+	for power*10 <= value {
+		power *= 10
+	}
+	return power
+}
+
+func ceilPow10(value int64) int64 {
+	power := int64(1)
+	for power < value {
+		power *= 10
+	}
+	return power
+}
+
+func (c *Chart) scaleY(value int64, minY int64, maxY int64) float64 {
+	plotHeight := float64(chartHeight - chartMarginTop - chartMarginBottom)
+	var fraction float64
+	if c.LogScale {
+		low := math.Log10(math.Max(1, float64(minY)))
+		high := math.Log10(math.Max(1, float64(maxY)))
+		if high <= low {
+			high = low + 1
+		}
+		fraction = (math.Log10(math.Max(1, float64(value))) - low) / (high - low)
+	} else {
+		fraction = float64(value-minY) / float64(maxY-minY)
+	}
+	return float64(chartMarginTop) + plotHeight*(1-fraction)
+}
+
+func scaleX(value int64, minX int64, maxX int64) float64 {
+	plotWidth := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	return float64(chartMarginLeft) + plotWidth*float64(value-minX)/float64(maxX-minX)
+}
+
+func (c *Chart) yTicks(minY int64, maxY int64) []int64 {
+	ticks := []int64{}
+	if c.LogScale {
+		// a tick of zero would never grow
+		if minY < 1 {
+			minY = 1
+		}
+		for tick := minY; tick <= maxY; tick *= 10 {
+			ticks = append(ticks, tick)
+		}
+		return ticks
+	}
+	step := (maxY - minY) / chartTicks
+	if step < 1 {
+		step = 1
+	}
+	for tick := minY; tick <= maxY; tick += step {
+		ticks = append(ticks, tick)
+	}
+	return ticks
+}
+
+func (c *Chart) RenderSVG(writer io.Writer) error {
+	minX, maxX, minY, maxY := c.bounds()
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="16" text-anchor="middle">%s</text>`+"\n", chartWidth/2, chartMarginTop/2+5, html.EscapeString(c.Title))
+
+	// Axes
+	left, right := chartMarginLeft, chartWidth-chartMarginRight
+	top, bottom := chartMarginTop, chartHeight-chartMarginBottom
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", left, bottom, right, bottom)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", left, top, left, bottom)
+	for _, tick := range c.yTicks(minY, maxY) {
+		y := c.scaleY(tick, minY, maxY)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e0e0e0"/>`+"\n", left, y, right, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%d</text>`+"\n", left-5, y+4, tick)
+	}
+	for i := 0; i <= chartTicks; i++ {
+		tick := minX + (maxX-minX)*int64(i)/chartTicks
+		x := scaleX(tick, minX, maxX)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="black"/>`+"\n", x, bottom, x, bottom+5)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%d</text>`+"\n", x, bottom+18, tick)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", (left+right)/2, chartHeight-10, html.EscapeString(c.XLabel))
+	yLabel := c.YLabel
+	if c.LogScale {
+		yLabel += " (log)"
+	}
+	fmt.Fprintf(&b, `<text x="15" y="%d" text-anchor="middle" transform="rotate(-90 15 %d)">%s</text>`+"\n", (top+bottom)/2, (top+bottom)/2, html.EscapeString(yLabel))
+
+	// Annotations, skipping any that fall outside the range of the data
+	for index, annotation := range c.Annotations {
+		if annotation.X < minX || annotation.X > maxX {
+			continue
+		}
+		x := scaleX(annotation.X, minX, maxX)
+		labelY := top + 12 + (index%4)*14
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#555555" stroke-dasharray="4,3"/>`+"\n", x, top, x, bottom)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" font-size="10" fill="#555555">%s</text>`+"\n", x+3, labelY, html.EscapeString(annotation.Label))
+	}
+
+	// Series and legend
+	for index, series := range c.Series {
+		color := chartColors[index%len(chartColors)]
+		points := make([]string, len(series.X))
+		for i, x := range series.X {
+			points[i] = fmt.Sprintf("%.1f,%.1f", scaleX(x, minX, maxX), c.scaleY(series.Y[i], minY, maxY))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`+"\n", color, strings.Join(points, " "))
+		legendY := top + 10 + index*18
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="3"/>`+"\n", right+10, legendY, right+30, legendY, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", right+35, legendY+4, html.EscapeString(series.Name))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(writer, b.String())
+	return err
+}
@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Percentile(t *testing.T) {
+	values := []int64{15, 20, 35, 40, 50}
+	tests := []struct {
+		percentile float64
+		expected   int64
+	}{
+		{percentile: 5, expected: 15},
+		{percentile: 30, expected: 20},
+		{percentile: 40, expected: 20},
+		{percentile: 50, expected: 35},
+		{percentile: 99, expected: 50},
+		{percentile: 100, expected: 50},
+	}
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d p%v", index+1, data.percentile), func(t *testing.T) {
+			assert.Equal(t, data.expected, percentile(values, data.percentile))
+		})
+	}
+	assert.Equal(t, int64(0), percentile([]int64{}, 50))
+	assert.Equal(t, []int64{15, 20, 35, 40, 50}, values, "percentile must not reorder the input")
+}
+
+func Test_Chart(t *testing.T) {
+	result := NewNeo4jResult([]string{"timestamp", "read:abc", "write:abc"})
+	result.add([]interface{}{int64(1), int64(10), int64(0)})
+	result.add([]interface{}{int64(2), int64(100), int64(20)})
+	result.add([]interface{}{int64(3), int64(1000), int64(30)})
+
+	tests := []struct {
+		name     string
+		logScale bool
+		points   []int
+		contains []string
+	}{
+		{name: "Linear", logScale: false, points: []int{3, 3}, contains: []string{">duration (ms)</text>", ">read:abc</text>", ">write:abc</text>"}},
+		{name: "Log", logScale: true, points: []int{3, 2}, contains: []string{">duration (ms) (log)</text>", ">10</text>", ">100</text>", ">1000</text>"}},
+	}
+
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d %s", index+1, data.name), func(t *testing.T) {
+			chart, err := NewChartFromResult("Latency <abc>", "duration (ms)", data.logScale, result)
+			assert.Nil(t, err)
+			assert.Equal(t, len(data.points), len(chart.Series))
+			for i, points := range data.points {
+				assert.Equal(t, points, len(chart.Series[i].X))
+			}
+
+			var svg strings.Builder
+			err = chart.RenderSVG(&svg)
+			assert.Nil(t, err)
+			text := svg.String()
+			assert.True(t, strings.HasPrefix(text, "<svg "))
+			assert.True(t, strings.HasSuffix(text, "</svg>\n"))
+			assert.Equal(t, len(data.points), strings.Count(text, "<polyline "))
+			assert.Contains(t, text, "Latency &lt;abc&gt;")
+			for _, expected := range data.contains {
+				assert.Contains(t, text, expected)
+			}
+		})
+	}
+
+	_, err := NewChartFromResult("Invalid", "", false, NewNeo4jResult([]string{"timestamp"}))
+	assert.NotNil(t, err)
+}
+
+func Test_LogChartWithoutPoints(t *testing.T) {
+	zeros := NewNeo4jResult([]string{"timestamp", "read:abc"})
+	zeros.add([]interface{}{int64(1), int64(0)})
+	zeros.add([]interface{}{int64(2), int64(0)})
+
+	for _, result := range []*Neo4jResult{NewNeo4jResult([]string{"timestamp", "read:abc"}), zeros} {
+		chart, err := NewChartFromResult("Latency", "duration (ms)", true, result)
+		assert.Nil(t, err)
+		var svg strings.Builder
+		assert.Nil(t, chart.RenderSVG(&svg))
+		assert.Contains(t, svg.String(), ">1</text>")
+		assert.Contains(t, svg.String(), ">10</text>")
+	}
+	assert.Equal(t, []int64{1, 10}, NewChart("Latency", "timestamp", "duration (ms)", true).yTicks(0, 10))
+}
+
+func Test_PercentilesTableGaps(t *testing.T) {
+	workload := mockTimingWorkload(t)
+	table, err := workload.PercentilesTable(50, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"timestamp", "read:abc", "write:abc"}, table.Header)
+	assert.Equal(t, [][]interface{}{{int64(1), int64(10), nil}, {int64(3), int64(12), nil}, {int64(5), int64(7), int64(30)}}, table.Rows)
+
+	chart, err := NewChartFromResult("p50", "duration (ms)", false, table)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 3, 5}, chart.Series[0].X)
+	assert.Equal(t, []int64{5}, chart.Series[1].X)
+}
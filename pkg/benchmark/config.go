@@ -0,0 +1,261 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// A configuration file allows the service to start benchmarking unattended, for example in a pod, instead of being
+// configured by a sequence of curl commands. CONFIG_FILE names a YAML file, or a JSON file if it ends in .json:
+//
+//     listenPort: 8099
+//     environment: firedrillxyz
+//     credentialsPath: /etc/neo4j-credentials
+//     workloads:
+//       readheavy: {readRate: 10, writeRate: 0.5}
+//       replication: {readRate: 1, writeRate: 1, lagRate: 0.2, causalRate: 1}
+//       contended: {readRate: 1, writeRate: 1, contention: {writers: 8, mode: hot, rate: 2, hotNodes: 3}}
+//     databases:
+//       - dbid: 143ea694
+//         credentials: 143ea694
+//       - alias: local
+//         uri: bolt://localhost:7687
+//         workload: readheavy
+//     autoStart: true
+//     reportPath: /data/latency-report.html
+//
+// Environment variables take precedence over the settings in the file. Rates are in queries per second, and a rate of
+// zero disables that part of the workload. Databases refer to credentials in the CREDENTIALS_PATH store, by default
+// those named after the dbid, since the file may not contain usernames or passwords. Each kind of query starts at its
+// rate however long the queries take, except that a query taking longer than the interval delays the next one, as the
+// queries of a kind run one at a time. Databases without a workload use the 'default' workload, which runs one read and
+// one write query per second unless it is redefined in the file. The lag rate is the number of markers written per
+// second to measure the replication lag to the other members of the cluster, and the causal rate the number of reads
+// per second after a write with its bookmark, which are both off unless set. A contention workload runs that many
+// concurrent writers, each with the given rate, which update the same node, a random one of a hot set of nodes, or one
+// node each, depending on the mode. With autoCommit: true the queries run in auto-commit transactions, which the driver
+// does not retry.
+
+type Config struct {
+	ListenPort      int                        `json:"listenPort,omitempty" yaml:"listenPort,omitempty"`
+	Environment     string                     `json:"environment,omitempty" yaml:"environment,omitempty"`
+	AddressTemplate string                     `json:"addressTemplate,omitempty" yaml:"addressTemplate,omitempty"`
+	CredentialsPath string                     `json:"credentialsPath,omitempty" yaml:"credentialsPath,omitempty"`
+	AuthFile        string                     `json:"authFile,omitempty" yaml:"authFile,omitempty"`
+	Workloads       map[string]WorkloadProfile `json:"workloads,omitempty" yaml:"workloads,omitempty"`
+	Databases       []JobSpec                  `json:"databases,omitempty" yaml:"databases,omitempty"`
+	AutoStart       bool                       `json:"autoStart,omitempty" yaml:"autoStart,omitempty"`
+	ReportPath      string                     `json:"reportPath,omitempty" yaml:"reportPath,omitempty"`
+}
+
+func ParseConfig(data []byte, isJSON bool) (*Config, error) {
+	config := &Config{}
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid configuration: %v", err))
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && err != io.EOF {
+			return nil, errors.New(fmt.Sprintf("Invalid configuration: %v", err))
+		}
+	}
+	return config, config.validate()
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data, strings.HasSuffix(path, ".json"))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded configuration of %d databases and %d workloads from %s", len(config.Databases), len(config.Workloads), path)
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	for name, profile := range c.Workloads {
+		if profile.ReadRate < 0 || profile.WriteRate < 0 || profile.LagRate < 0 || profile.CausalRate < 0 {
+			return errors.New(fmt.Sprintf("Invalid configuration: workload '%s' has a negative rate", name))
+		}
+		if profile.ReadRate == 0 && profile.WriteRate == 0 && profile.LagRate == 0 && profile.CausalRate == 0 && profile.Contention == (ContentionProfile{}) {
+			return errors.New(fmt.Sprintf("Invalid configuration: workload '%s' has no read, write, lag or causal rate or contention", name))
+		}
+		if profile.Contention != (ContentionProfile{}) {
+			if err := profile.Contention.validate(); err != nil {
+				return errors.New(fmt.Sprintf("Invalid configuration: workload '%s': %v", name, err))
+			}
+		}
+	}
+	for i, spec := range c.Databases {
+		if spec.Username != "" || spec.Password != "" {
+			return errors.New(fmt.Sprintf("Invalid configuration: database %d has a username or password, refer to credentials in the credential store instead", i+1))
+		}
+		if spec.Workload != "" && spec.Workload != defaultWorkload {
+			if _, ok := c.Workloads[spec.Workload]; !ok {
+				return errors.New(fmt.Sprintf("Invalid configuration: database %d refers to unknown workload '%s'", i+1, spec.Workload))
+			}
+		}
+	}
+	return nil
+}
+
+// The workload profiles of the configuration, including the default workload
+func (c *Config) profiles() map[string]WorkloadProfile {
+	profiles := map[string]WorkloadProfile{defaultWorkload: defaultProfile}
+	for name, profile := range c.Workloads {
+		profiles[name] = profile
+	}
+	return profiles
+}
+
+// The job specification and resolved workload profile of a database added from the configuration file, used to find
+// out which jobs changed when the configuration is reloaded
+type configuredJob struct {
+	spec    JobSpec
+	profile WorkloadProfile
+}
+
+// The changes made to the workload by applying a configuration
+type ConfigChanges struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+	Errors    []string `json:"errors"`
+}
+
+func (c *ConfigChanges) addError(name string, err error) {
+	log.Printf("Failed to configure database '%s': %v", name, err)
+	c.Errors = append(c.Errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// Apply the configuration to the workload, by adding, removing and replacing only the jobs that were added by a
+// previous configuration and have changed, so that the results of all other jobs are kept. Databases that fail to be
+// added are reported and skipped, so that one unavailable database does not prevent benchmarking the others, and
+// will be retried when the configuration is next reloaded.
+func (s *Server) applyConfig(config *Config, workload *Workload) ConfigChanges {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+	s.config = config
+	changes := ConfigChanges{[]string{}, []string{}, []string{}, []string{}, []string{}}
+	jobs := map[string]*Neo4jJob{}
+	configured := map[string]configuredJob{}
+	for _, spec := range config.Databases {
+		// the configuration lock is held, so the profiles are taken from the configuration being applied
+		job, err := s.newNeo4jJobWith(config.profiles(), spec, nil)
+		if err == nil && jobs[job.dbid] != nil {
+			err = newWorkloadError(ErrConflict, "Database '%s' is configured more than once", job.dbid)
+		}
+		if err != nil {
+			changes.addError(nameFor(spec), err)
+			continue
+		}
+		jobs[job.dbid] = job
+		configured[job.dbid] = configuredJob{spec, job.profile}
+	}
+	for _, name := range sortedJobNames(s.configured) {
+		if _, ok := configured[name]; !ok {
+			if job, err := s.findJob(workload, name); err == nil {
+				workload.Remove(job)
+			}
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	for _, name := range sortedJobNames(configured) {
+		previous, existed := s.configured[name]
+		var err error
+		switch {
+		case !existed:
+			if err = workload.Add(jobs[name]); err == nil {
+				changes.Added = append(changes.Added, name)
+			}
+		case previous == configured[name]:
+			changes.Unchanged = append(changes.Unchanged, name)
+		default:
+			if err = workload.Replace(jobs[name]); err == nil {
+				changes.Updated = append(changes.Updated, name)
+			}
+		}
+		if err != nil {
+			changes.addError(name, err)
+			if existed {
+				configured[name] = previous
+			} else {
+				delete(configured, name)
+			}
+		}
+	}
+	s.configured = configured
+	if config.AutoStart && !workload.started {
+		if _, err := workload.Start(); err != nil {
+			changes.addError("workload", err)
+		}
+	}
+	return changes
+}
+
+func sortedJobNames(jobs map[string]configuredJob) []string {
+	names := []string{}
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reload the configuration file, and apply any changes to the workload
+func (s *Server) ReloadConfig(workload *Workload) (*ConfigChanges, error) {
+	if s.configPath == "" {
+		return nil, newWorkloadError(ErrInvalid, "No configuration file to reload, CONFIG_FILE is not set")
+	}
+	config, err := LoadConfig(s.configPath)
+	if err != nil {
+		return nil, newWorkloadError(ErrInvalid, "Failed to load configuration from %s: %v", s.configPath, err)
+	}
+	changes := s.applyConfig(config, workload)
+	log.Printf("Reloaded configuration: added %v, removed %v, updated %v", changes.Added, changes.Removed, changes.Updated)
+	return &changes, nil
+}
+
+// Reload the configuration whenever the process receives SIGHUP
+func (s *Server) reloadOnSignal(workload *Workload) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		if _, err := s.ReloadConfig(workload); err != nil {
+			log.Printf("Failed to reload configuration: %v", err)
+		}
+	}
+}
+
+func (s *Server) apiReloadHandler(writer http.ResponseWriter, request *http.Request, workload *Workload) {
+	if request.Method != http.MethodPost {
+		s.methodNotAllowed(writer, request, http.MethodPost)
+		return
+	}
+	changes, err := s.ReloadConfig(workload)
+	if err != nil {
+		s.handleAPIError(writer, "Failed to reload configuration", err)
+	} else {
+		s.writeJSON(writer, http.StatusOK, changes)
+	}
+}
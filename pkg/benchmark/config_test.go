@@ -0,0 +1,171 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+listenPort: 8099
+environment: testenv
+workloads:
+  readheavy: {readRate: 10, writeRate: 0.5}
+  readonly:
+    readRate: 2
+    writeRate: 0
+databases:
+  - dbid: abc
+  - alias: local
+    uri: bolt://localhost:7687
+    database: movies
+    workload: readheavy
+autoStart: true
+`
+
+func Test_ParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(testConfig), false)
+	assert.Nil(t, err)
+	assert.Equal(t, &Config{
+		ListenPort:  8099,
+		Environment: "testenv",
+		Workloads:   map[string]WorkloadProfile{"readheavy": {ReadRate: 10, WriteRate: 0.5}, "readonly": {ReadRate: 2}},
+		Databases:   []JobSpec{{Dbid: "abc"}, {Alias: "local", URI: "bolt://localhost:7687", Database: "movies", Workload: "readheavy"}},
+		AutoStart:   true,
+	}, config)
+	assert.Equal(t, map[string]WorkloadProfile{"default": defaultProfile, "readheavy": {ReadRate: 10, WriteRate: 0.5}, "readonly": {ReadRate: 2}}, config.profiles())
+
+	json, err := ParseConfig([]byte(`{"listenPort":8099,"environment":"testenv","databases":[{"dbid":"abc"}]}`), true)
+	assert.Nil(t, err)
+	assert.Equal(t, &Config{ListenPort: 8099, Environment: "testenv", Databases: []JobSpec{{Dbid: "abc"}}}, json)
+
+	empty, err := ParseConfig([]byte(""), false)
+	assert.Nil(t, err)
+	assert.Equal(t, &Config{}, empty)
+
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{content: "listenport: 8099", expected: "Invalid configuration: yaml: unmarshal errors:\n  line 1: field listenport not found in type benchmark.Config"},
+		{content: "workloads: {none: {readRate: 0, writeRate: 0}}", expected: "Invalid configuration: workload 'none' has no read, write, lag or causal rate or contention"},
+		{content: "workloads: {bad: {readRate: -1, writeRate: 1}}", expected: "Invalid configuration: workload 'bad' has a negative rate"},
+		{content: "databases: [{dbid: abc, workload: other}]", expected: "Invalid configuration: database 1 refers to unknown workload 'other'"},
+		{content: "databases: [{dbid: abc}, {dbid: xyz, password: secret}]", expected: "Invalid configuration: database 2 has a username or password, refer to credentials in the credential store instead"},
+	}
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d", index+1), func(t *testing.T) {
+			_, err := ParseConfig([]byte(data.content), false)
+			assert.NotNil(t, err)
+			assert.Equal(t, data.expected, err.Error())
+		})
+	}
+}
+
+// Answers queries straight away, so that tests of workloads with higher rates do not wait for the usual slow queries
+type TestInstantSessionMaker struct {
+	TestSessionMaker
+}
+
+type TestInstantSession struct {
+	TestQuerySession
+}
+
+func (m *TestInstantSessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
+	return &TestInstantSession{}, nil
+}
+
+func (r *TestInstantSession) RunCypherQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	result := NewNeo4jResult([]string{"name"})
+	result.add([]interface{}{"value"})
+	return result, nil
+}
+
+func Test_ApplyConfig(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := NewWorkload(&TestInstantSessionMaker{})
+	config, err := ParseConfig([]byte(testConfig), false)
+	assert.Nil(t, err)
+	config.Workloads["readonly"] = WorkloadProfile{ReadRate: 20}
+	config.Databases = append(config.Databases, JobSpec{Dbid: "abc"}, JobSpec{Dbid: "xyz", Workload: "readonly"})
+	changes := s.applyConfig(config, workload)
+	defer workload.Stop()
+	assert.Equal(t, []string{"abc", "local", "xyz"}, changes.Added)
+	assert.Equal(t, []string{"abc: Database 'abc' is configured more than once"}, changes.Errors)
+
+	statuses := makeJobStatuses(workload.List(), workload)
+	assert.Equal(t, 3, len(statuses), "The duplicate database should have been skipped")
+	assert.Equal(t, []string{"abc", "local", "xyz"}, []string{statuses[0].Dbid, statuses[1].Dbid, statuses[2].Dbid})
+	assert.Equal(t, "readheavy", statuses[1].Workload)
+	assert.Equal(t, 50*time.Millisecond, intervalOf(20))
+	assert.True(t, workload.running)
+
+	time.Sleep(200 * time.Millisecond)
+	read, _ := workload.CountsFor("xyz", "read")
+	write, _ := workload.CountsFor("xyz", "write")
+	assert.GreaterOrEqual(t, read, 1, "Expected reads to have started")
+	assert.Equal(t, 0, write, "Expected no writes with a write rate of zero")
+}
+
+func Test_ReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	s, _ := mockServer(t)
+	workload := NewWorkload(&TestInstantSessionMaker{})
+	handler := s.apiHandler(workload)
+	s.configPath = filepath.Join(dir, "config.yaml")
+	writeTestFile(t, s.configPath, testConfig)
+	changes, err := s.ReloadConfig(workload)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"abc", "local"}, changes.Added)
+	defer workload.Stop()
+	assert.Nil(t, workload.Add(NewNeo4jJob(*NewNeo4j("api", "bolt://api", "neo4j", "neo4j", "secret"))))
+	time.Sleep(300 * time.Millisecond)
+
+	tests := []struct {
+		config     string
+		statuscode int
+		expected   string
+	}{
+		{config: testConfig, statuscode: http.StatusOK, expected: `{"added":[],"removed":[],"updated":[],"unchanged":["abc","local"],"errors":[]}`},
+		{config: strings.Replace(testConfig, "workload: readheavy", "workload: readonly", 1), statuscode: http.StatusOK, expected: `{"added":[],"removed":[],"updated":["local"],"unchanged":["abc"],"errors":[]}`},
+		{config: strings.Replace(testConfig, "  - dbid: abc\n", "  - dbid: xyz\n", 1), statuscode: http.StatusOK, expected: `{"added":["xyz"],"removed":["abc"],"updated":["local"],"unchanged":[],"errors":[]}`},
+		{config: "databases: [{dbid: xyz, workload: other}]", statuscode: http.StatusBadRequest, expected: `{"error":{"status":400,"message":"Failed to reload configuration","detail":"Failed to load configuration from ` + s.configPath + `: Invalid configuration: database 1 refers to unknown workload 'other'"}}`},
+	}
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d", index+1), func(t *testing.T) {
+			writeTestFile(t, s.configPath, data.config)
+			request := httptest.NewRequest("POST", "/api/v1/reload", nil)
+			responseRecorder := httptest.NewRecorder()
+			handler(responseRecorder, request)
+			response := responseRecorder.Result()
+			body, _ := ioutil.ReadAll(response.Body)
+			assert.Equal(t, data.expected, string(body))
+			assert.Equal(t, data.statuscode, response.StatusCode)
+		})
+	}
+
+	names := []string{}
+	for _, job := range workload.List() {
+		names = append(names, job.dbid)
+	}
+	assert.Equal(t, []string{"api", "local", "xyz"}, names, "Jobs added through the API should not be affected by reloading")
+	read, _ := workload.CountsFor("local", "read")
+	assert.GreaterOrEqual(t, read, 1, "Results of reconfigured jobs should be kept")
+
+	_, err = workload.Stop()
+	assert.Nil(t, err)
+	writeTestFile(t, s.configPath, testConfig)
+	_, err = s.ReloadConfig(workload)
+	assert.Nil(t, err)
+	assert.False(t, workload.running, "Reloading should not start a workload that was stopped")
+}
@@ -0,0 +1,159 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The read and write queries of a job run one at a time, so they never wait for each other's locks. A workload with a
+// contention profile also runs a number of concurrent writers, which each increment the value of one of a set of
+// ClientBenchmarkContention nodes: all the same node, a random one of a small hot set, or one node per writer. Their
+// durations are recorded as 'contention' results, and the deadlocks and other transient errors that the driver retried
+// are counted, so that the latency and the retries at different levels of contention can be compared.
+
+const (
+	contentionSame     = "same"
+	contentionHot      = "hot"
+	contentionDisjoint = "disjoint"
+)
+
+var contentionModes = []string{contentionSame, contentionHot, contentionDisjoint}
+
+const defaultHotNodes = 5
+
+// The concurrent writers of a job, each running rate writes per second, which are disabled if there are none
+type ContentionProfile struct {
+	Writers  int     `json:"writers" yaml:"writers"`
+	Mode     string  `json:"mode" yaml:"mode"` // same, hot or disjoint
+	Rate     float64 `json:"rate" yaml:"rate"`
+	HotNodes int     `json:"hotNodes,omitempty" yaml:"hotNodes,omitempty"` // the size of the hot set, 5 by default
+}
+
+func (c ContentionProfile) enabled() bool {
+	return c.Writers > 0
+}
+
+func (c ContentionProfile) validate() error {
+	valid := false
+	for _, mode := range contentionModes {
+		valid = valid || c.Mode == mode
+	}
+	if !valid {
+		return errors.New(fmt.Sprintf("contention mode must be one of %s", strings.Join(contentionModes, ", ")))
+	}
+	if c.Writers <= 0 || c.Rate <= 0 || c.HotNodes < 0 {
+		return errors.New("contention needs a positive number of writers and rate")
+	}
+	return nil
+}
+
+// The number of nodes the writers update
+func (c ContentionProfile) nodes() int {
+	switch c.Mode {
+	case contentionHot:
+		if c.HotNodes > 0 {
+			return c.HotNodes
+		}
+		return defaultHotNodes
+	case contentionDisjoint:
+		return c.Writers
+	default:
+		return 1
+	}
+}
+
+// The node the writer updates next
+func (c ContentionProfile) keyFor(writer int) int {
+	switch c.Mode {
+	case contentionHot:
+		return rand.Intn(c.nodes())
+	case contentionDisjoint:
+		return writer
+	default:
+		return 0
+	}
+}
+
+// Create the nodes before the writers start, since concurrent MERGE statements could create the same node twice
+func (n *Neo4jJob) createContentionModel(maker SessionMaker) error {
+	runner, err := maker.NewQuerySession(n.neo4j, neo4j.AccessModeWrite)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+	query := fmt.Sprintf("UNWIND range(0, %d) AS key MERGE (c:ClientBenchmarkContention {key: key}) ON CREATE SET c.value = 0 RETURN count(c)", n.profile.Contention.nodes()-1)
+	_, err = runner.RunCypherQuery(neo4j.AccessModeWrite, query)
+	return err
+}
+
+func (n *Neo4jJob) runContentionWriter(ch chan Message, maker SessionMaker, writer int, interval time.Duration) {
+	runner, err := maker.NewQuerySession(n.neo4j, neo4j.AccessModeWrite)
+	if err != nil {
+		log.Printf("Failed to create runner for contention writer %d against '%s': %v", writer, n.dbid, err)
+		ch <- Message{verb: "contention:error", dbid: n.dbid, value: -1, message: err.Error()}
+		return
+	}
+	defer runner.Close()
+	countErrors := 0
+	maxErrors := 10
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for countErrors < maxErrors && n.tick(ticker) {
+		query := fmt.Sprintf("MATCH (c:ClientBenchmarkContention {key: %d}) SET c.value = c.value + 1 RETURN c.value", n.profile.Contention.keyFor(writer))
+		started := time.Now()
+		result, err := n.run(runner, neo4j.AccessModeWrite, query)
+		if err != nil {
+			log.Printf("Error running contention write %d against '%s': %v", writer, n.dbid, err)
+			countErrors += 1
+			n.sendFailedRetries(ch, "contention", err)
+			ch <- Message{verb: "contention:error", dbid: n.dbid, value: int64(countErrors), message: err.Error()}
+			continue
+		}
+		duration := time.Since(started)
+		n.sendRetries(ch, "contention", result)
+		ch <- Message{verb: "contention", dbid: n.dbid, value: duration.Milliseconds(), server: result.server, timing: result.timing.withTotal(duration)}
+	}
+	log.Printf("Finishing contention writer %d against '%s' (errors=%d)", writer, n.dbid, countErrors)
+}
+
+// The latencies and retries of the contention writers of each job with any, for one job or for all jobs if dbid is
+// empty. Failed writes are those that still failed after the retries of the driver.
+func (w *Workload) ContentionSummary(dbid string) *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "mode", "writers", "nodes", "count", "p50", "p99", "max", "retries", "deadlocks", "failed"})
+	failures := map[string]int{}
+	deadlocks := map[string]int{}
+	for _, event := range w.Events() {
+		if event.kind == "contention:error" {
+			failures[event.dbid]++
+			if strings.Contains(event.message, "DeadlockDetected") {
+				deadlocks[event.dbid]++
+			}
+		}
+	}
+	for _, client := range w.List() {
+		contention := client.profile.Contention
+		if !contention.enabled() || (dbid != "" && client.dbid != dbid) {
+			continue
+		}
+		durations := w.results.For(client.dbid, "contention").durations
+		retries := w.retries.For(client.dbid, "contention")
+		result.add([]interface{}{client.dbid, contention.Mode, contention.Writers, contention.nodes(), len(durations),
+			percentile(durations, 50), percentile(durations, 99), percentile(durations, 100),
+			retries.Retries, retries.Deadlocks + deadlocks[client.dbid], failures[client.dbid]})
+	}
+	return result
+}
+
+func (s *Server) apiContentionHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, workload.ContentionSummary(job.dbid))
+}
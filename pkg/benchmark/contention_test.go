@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"errors"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Answers each contention write straight away, after a deadlock that the driver retried, or fails it if failing is
+// set, and remembers the queries
+type TestContentionSessionMaker struct {
+	TestSessionMaker
+	lock    sync.Mutex
+	queries []string
+	failing bool
+}
+
+type TestContentionSession struct {
+	TestQuerySession
+	maker *TestContentionSessionMaker
+}
+
+func (m *TestContentionSessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
+	return &TestContentionSession{maker: m}, nil
+}
+
+func (r *TestContentionSession) RunCypherQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	r.maker.lock.Lock()
+	defer r.maker.lock.Unlock()
+	r.maker.queries = append(r.maker.queries, query)
+	if r.maker.failing {
+		return nil, errors.New("connection refused")
+	}
+	result := NewNeo4jResult([]string{"c.value"})
+	result.add([]interface{}{int64(len(r.maker.queries))})
+	result.attempts = []Attempt{{30, "Neo.TransientError.Transaction.DeadlockDetected"}, {5, ""}}
+	return result, nil
+}
+
+func Test_ContentionProfile(t *testing.T) {
+	tests := []struct {
+		profile ContentionProfile
+		nodes   int
+		keys    []int
+		err     string
+	}{
+		{ContentionProfile{Writers: 4, Mode: "same", Rate: 1}, 1, []int{0, 0, 0, 0}, ""},
+		{ContentionProfile{Writers: 4, Mode: "disjoint", Rate: 1}, 4, []int{0, 1, 2, 3}, ""},
+		{ContentionProfile{Writers: 4, Mode: "hot", Rate: 1, HotNodes: 1}, 1, []int{0, 0, 0, 0}, ""},
+		{ContentionProfile{Writers: 4, Mode: "hot", Rate: 1}, defaultHotNodes, nil, ""},
+		{ContentionProfile{Writers: 4, Mode: "cold", Rate: 1}, 1, nil, "contention mode must be one of same, hot, disjoint"},
+		{ContentionProfile{Mode: "same", Rate: 1}, 1, nil, "contention needs a positive number of writers and rate"},
+		{ContentionProfile{Writers: 4, Mode: "same"}, 1, nil, "contention needs a positive number of writers and rate"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.nodes, test.profile.nodes(), "%v", test.profile)
+		if test.keys != nil {
+			keys := []int{}
+			for writer := 0; writer < test.profile.Writers; writer++ {
+				keys = append(keys, test.profile.keyFor(writer))
+			}
+			assert.Equal(t, test.keys, keys, "%v", test.profile)
+		}
+		err := test.profile.validate()
+		if test.err == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+
+	_, err := ParseConfig([]byte("workloads: {contended: {readRate: 1, writeRate: 1, contention: {writers: 4, mode: warm, rate: 1}}}"), false)
+	assert.EqualError(t, err, "Invalid configuration: workload 'contended': contention mode must be one of same, hot, disjoint")
+	config, err := ParseConfig([]byte("workloads: {contended: {readRate: 0, writeRate: 0, contention: {writers: 4, mode: hot, rate: 2, hotNodes: 3}}}"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, ContentionProfile{Writers: 4, Mode: "hot", Rate: 2, HotNodes: 3}, config.Workloads["contended"].Contention)
+}
+
+func Test_ErrorCode(t *testing.T) {
+	assert.Equal(t, "Neo.TransientError.Transaction.DeadlockDetected", errorCode(&neo4j.Neo4jError{Code: "Neo.TransientError.Transaction.DeadlockDetected", Msg: "deadlock"}))
+	assert.Equal(t, "connection reset", errorCode(errors.New("connection reset")))
+}
+
+func Test_ContentionWriters(t *testing.T) {
+	maker := &TestContentionSessionMaker{}
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	job.profile = WorkloadProfile{Contention: ContentionProfile{Writers: 3, Mode: "disjoint", Rate: 20}}
+	ch := make(chan Message, 1000)
+	job.Start(ch, maker)
+	time.Sleep(200 * time.Millisecond)
+	job.Stop()
+	job.workers.Wait()
+	close(ch)
+
+	assert.Equal(t, "UNWIND range(0, 2) AS key MERGE (c:ClientBenchmarkContention {key: key}) ON CREATE SET c.value = 0 RETURN count(c)", maker.queries[0])
+	keys := map[string]bool{}
+	for _, query := range maker.queries[1:] {
+		keys[strings.Split(query, " ")[3]] = true
+	}
+	assert.Equal(t, map[string]bool{"0})": true, "1})": true, "2})": true}, keys)
+	verbs := map[string]int{}
+	for msg := range ch {
+		verbs[msg.verb]++
+	}
+	assert.True(t, verbs["contention"] >= 3)
+	assert.Equal(t, verbs["contention"], verbs["contention:retry"])
+	assert.Equal(t, len(maker.queries)-1, verbs["contention"])
+}
+
+func Test_ContentionWriterStopsAfterErrors(t *testing.T) {
+	maker := &TestContentionSessionMaker{failing: true}
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	ch := make(chan Message, 100)
+	job.runContentionWriter(ch, maker, 0, time.Millisecond)
+	close(ch)
+
+	assert.Equal(t, 10, len(maker.queries))
+	values := []int64{}
+	for msg := range ch {
+		assert.Equal(t, "contention:error", msg.verb)
+		values = append(values, msg.value)
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, values)
+}
+
+func Test_ContentionSummary(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := mockPublishedWorkload(t)
+	job, err := s.findJob(workload, "abc")
+	assert.Nil(t, err)
+	job.profile.Contention = ContentionProfile{Writers: 8, Mode: "hot", Rate: 2, HotNodes: 3}
+	for i := int64(1); i <= 10; i++ {
+		workload.record(Message{verb: "contention", dbid: "abc", value: 10 * i})
+	}
+	workload.record(Message{verb: "contention:retry", dbid: "abc", value: 30, message: "Neo.TransientError.Transaction.DeadlockDetected"})
+	workload.record(Message{verb: "contention:retry", dbid: "abc", value: 40, message: "Neo.TransientError.Transaction.DeadlockDetected"})
+	workload.record(Message{verb: "contention:retry", dbid: "abc", value: 50, message: "Neo.TransientError.Transaction.LockAcquisitionTimeout"})
+	workload.record(Message{verb: "contention:error", dbid: "abc", value: -1, message: "Neo4jError: Neo.TransientError.Transaction.DeadlockDetected (deadlock)"})
+
+	summary := workload.ContentionSummary("")
+	assert.Equal(t, []string{"dbid", "mode", "writers", "nodes", "count", "p50", "p99", "max", "retries", "deadlocks", "failed"}, summary.Header)
+	assert.Equal(t, [][]interface{}{{"abc", "hot", 8, 3, 10, int64(50), int64(100), int64(100), 3, 3, 1}}, summary.Rows)
+
+	recorder := httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/xyz/contention", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"Header":["dbid","mode","writers","nodes","count","p50","p99","max","retries","deadlocks","failed"],"Rows":[]}`, recorder.Body.String())
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	var markdown strings.Builder
+	assert.Nil(t, report.WriteMarkdown(&markdown))
+	assert.Contains(t, markdown.String(), "## Write contention (ms)\n\n| dbid | mode | writers | nodes | count | p50 | p99 | max | retries | deadlocks | failed |")
+	assert.Contains(t, markdown.String(), "| abc | hot | 8 | 3 | 10 | 50 | 100 | 100 | 3 | 3 | 1 |")
+}
@@ -0,0 +1,215 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The credential store keeps the Neo4j usernames and passwords on the server, so they no longer need to be passed on
+// every request. CREDENTIALS_PATH names either a file or a directory, typically a mounted Kubernetes secret:
+//
+// A file contains one credential per line as 'name username password' (or a JSON object of the form
+// {"name":{"username":"neo4j","password":"..."}} if the file name ends in .json).
+//
+// A directory contains one entry per credential, either a sub-directory 'name' with files 'username' and 'password',
+// or a file 'name' containing only the password, in which case the username is 'neo4j'.
+//
+// The store is re-read periodically so that rotated secrets are picked up by the next session created for a job. The
+// goroutines of a running job keep the sessions, and so the drivers, they started with, so the running jobs that use
+// credentials that changed are restarted with new sessions. Jobs refer to credentials by name, which defaults to the
+// dbid, and passwords are never returned or logged.
+
+const defaultUsername = "neo4j"
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type CredentialStore struct {
+	path        string
+	lock        sync.RWMutex
+	credentials map[string]Credentials
+}
+
+func NewCredentialStore(path string) (*CredentialStore, error) {
+	store := &CredentialStore{path: path, credentials: map[string]Credentials{}}
+	if _, err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func readCredentialFile(path string) (map[string]Credentials, error) {
+	credentials := map[string]Credentials{}
+	if strings.HasSuffix(path, ".json") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &credentials); err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid credentials file %s: %v", path, err))
+		}
+		return credentials, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			// Do not include the line itself, as it probably contains a password
+			return nil, errors.New(fmt.Sprintf("Invalid credentials on line %d of %s: expected 'name username password'", line, path))
+		}
+		credentials[fields[0]] = Credentials{fields[1], fields[2]}
+	}
+	return credentials, scanner.Err()
+}
+
+func readSecretValue(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func readCredentialDirectory(path string) (map[string]Credentials, error) {
+	credentials := map[string]Credentials{}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		// Kubernetes mounts secrets using hidden directories and symbolic links like '..data'
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		entryPath := filepath.Join(path, name)
+		info, err := os.Stat(entryPath)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			username, err := readSecretValue(filepath.Join(entryPath, "username"))
+			if os.IsNotExist(err) {
+				username = defaultUsername
+			} else if err != nil {
+				return nil, err
+			}
+			password, err := readSecretValue(filepath.Join(entryPath, "password"))
+			if err != nil {
+				return nil, err
+			}
+			credentials[name] = Credentials{username, password}
+		} else {
+			password, err := readSecretValue(entryPath)
+			if err != nil {
+				return nil, err
+			}
+			credentials[name] = Credentials{defaultUsername, password}
+		}
+	}
+	return credentials, nil
+}
+
+// Re-read all credentials, returning the sorted names of those that were added, changed or removed since the last time
+// they were read
+func (c *CredentialStore) Reload() ([]string, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return nil, err
+	}
+	var credentials map[string]Credentials
+	if info.IsDir() {
+		credentials, err = readCredentialDirectory(c.path)
+	} else {
+		credentials, err = readCredentialFile(c.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	changed := []string{}
+	for name, credential := range credentials {
+		if previous, ok := c.credentials[name]; !ok || previous != credential {
+			changed = append(changed, name)
+		}
+	}
+	for name := range c.credentials {
+		if _, ok := credentials[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	c.credentials = credentials
+	return changed, nil
+}
+
+func (c *CredentialStore) Get(name string) (Credentials, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	credential, ok := c.credentials[name]
+	if !ok {
+		return Credentials{}, newWorkloadError(ErrNotFound, "Could not find credentials '%s'", name)
+	}
+	return credential, nil
+}
+
+func (c *CredentialStore) Has(name string) bool {
+	_, err := c.Get(name)
+	return err == nil
+}
+
+func (c *CredentialStore) Names() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	names := []string{}
+	for name := range c.credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Periodically reload the credentials until the done channel is closed, so that rotated secrets are picked up, and
+// pass the names of the credentials that changed to rotated
+func (c *CredentialStore) Watch(interval time.Duration, done chan struct{}, rotated func(names []string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := c.Reload()
+			if err != nil {
+				log.Printf("Failed to reload credentials from %s: %v", c.path, err)
+			} else if len(changed) > 0 {
+				log.Printf("Reloaded %d credentials from %s, of which %v changed", len(c.Names()), c.path, changed)
+				rotated(changed)
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package benchmark
+
+import (
+	"errors"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func Test_CredentialStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "table.txt"), "# name username password\nabc neo4j secret\n\nxyz admin other\n")
+	writeTestFile(t, filepath.Join(dir, "table.json"), `{"abc":{"username":"neo4j","password":"secret"}}`)
+	writeTestFile(t, filepath.Join(dir, "invalid.txt"), "abc neo4j\n")
+	writeTestFile(t, filepath.Join(dir, "secrets", "abc"), "secret\n")
+	writeTestFile(t, filepath.Join(dir, "secrets", "xyz", "username"), "admin")
+	writeTestFile(t, filepath.Join(dir, "secrets", "xyz", "password"), "other\n")
+	writeTestFile(t, filepath.Join(dir, "secrets", "..data", "abc"), "ignored")
+
+	tests := []struct {
+		path     string
+		expected map[string]Credentials
+	}{
+		{path: "table.txt", expected: map[string]Credentials{"abc": {"neo4j", "secret"}, "xyz": {"admin", "other"}}},
+		{path: "table.json", expected: map[string]Credentials{"abc": {"neo4j", "secret"}}},
+		{path: "secrets", expected: map[string]Credentials{"abc": {"neo4j", "secret"}, "xyz": {"admin", "other"}}},
+	}
+	for _, data := range tests {
+		t.Run(data.path, func(t *testing.T) {
+			store, err := NewCredentialStore(filepath.Join(dir, data.path))
+			assert.Nil(t, err)
+			for name, expected := range data.expected {
+				actual, err := store.Get(name)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, actual)
+			}
+			assert.Equal(t, len(data.expected), len(store.Names()))
+			_, err = store.Get("missing")
+			assert.True(t, errors.Is(err, ErrNotFound))
+			assert.Equal(t, "Could not find credentials 'missing'", err.Error())
+		})
+	}
+
+	_, err = NewCredentialStore(filepath.Join(dir, "invalid.txt"))
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "neo4j")
+
+	t.Run("rotation", func(t *testing.T) {
+		store, err := NewCredentialStore(filepath.Join(dir, "secrets"))
+		assert.Nil(t, err)
+		changed, err := store.Reload()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{}, changed)
+		writeTestFile(t, filepath.Join(dir, "secrets", "abc"), "rotated\n")
+		changed, err = store.Reload()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"abc"}, changed)
+		actual, _ := store.Get("abc")
+		assert.Equal(t, Credentials{"neo4j", "rotated"}, actual)
+	})
+}
+
+func Test_NewNeo4jJobCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "table.txt"), "abc neo4j secret\nshared neo4j other\n")
+	s, _ := mockServer(t)
+	s.credentials, err = NewCredentialStore(filepath.Join(dir, "table.txt"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		spec        JobSpec
+		basic       []string
+		credentials string
+		username    string
+		err         string
+	}{
+		{spec: JobSpec{Dbid: "abc"}, basic: []string{"ignore", "ignore"}, credentials: "abc"},
+		{spec: JobSpec{Dbid: "xyz", Credentials: "shared"}, credentials: "shared"},
+		{spec: JobSpec{Dbid: "abc", Username: "neo4j", Password: "explicit"}, username: "neo4j"},
+		{spec: JobSpec{Dbid: "xyz"}, basic: []string{"basic", "password"}, username: "basic"},
+		{spec: JobSpec{Dbid: "xyz", Credentials: "missing"}, err: "Unknown credentials 'missing'"},
+	}
+	for _, data := range tests {
+		t.Run(data.spec.Dbid+"_"+data.spec.Credentials, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/api/v1/jobs", nil)
+			if len(data.basic) == 2 {
+				request.SetBasicAuth(data.basic[0], data.basic[1])
+			}
+			job, err := s.newNeo4jJob(data.spec, request)
+			if data.err != "" {
+				assert.NotNil(t, err)
+				assert.True(t, errors.Is(err, ErrInvalid))
+				assert.Equal(t, data.err, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, data.credentials, job.neo4j.credentials)
+			assert.Equal(t, data.username, job.neo4j.username)
+		})
+	}
+}
+
+// Counts the sessions created for each job
+type TestCountingSessionMaker struct {
+	TestInstantSessionMaker
+	lock     sync.Mutex
+	sessions map[string]int
+}
+
+func (m *TestCountingSessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sessions[n.dbid]++
+	return m.TestInstantSessionMaker.NewQuerySession(n, accessMode)
+}
+
+func (m *TestCountingSessionMaker) count(dbid string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.sessions[dbid]
+}
+
+func Test_RestartWithCredentials(t *testing.T) {
+	maker := &TestCountingSessionMaker{sessions: map[string]int{}}
+	workload := NewWorkload(maker)
+	assert.Nil(t, workload.Add(NewNeo4jJob(*NewNeo4jWithCredentials("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "shared"))))
+	assert.Nil(t, workload.Add(NewNeo4jJob(*NewNeo4jWithCredentials("xyz", "neo4j+s://xyz-testenv.databases.neo4j.io", "neo4j", "xyz"))))
+	_, err := workload.Start()
+	assert.Nil(t, err)
+	defer workload.Stop()
+	time.Sleep(100 * time.Millisecond)
+	abc, xyz := maker.count("abc"), maker.count("xyz")
+
+	workload.RestartWithCredentials([]string{"shared", "other"})
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, maker.count("abc") > abc, "The job using the rotated credentials should have created new sessions")
+	assert.Equal(t, xyz, maker.count("xyz"))
+	for _, client := range workload.List() {
+		assert.True(t, client.isRunning(), client.dbid)
+	}
+	restarts := []string{}
+	for _, event := range workload.Events() {
+		if event.kind == "restart" {
+			restarts = append(restarts, event.dbid+": "+event.message)
+		}
+	}
+	assert.Equal(t, []string{"abc: credentials 'shared' rotated"}, restarts)
+}
+
+func Test_CredentialStoreWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeTestFile(t, filepath.Join(dir, "secrets", "abc"), "secret\n")
+	store, err := NewCredentialStore(filepath.Join(dir, "secrets"))
+	assert.Nil(t, err)
+
+	rotated := make(chan []string, 10)
+	done := make(chan struct{})
+	defer close(done)
+	go store.Watch(10*time.Millisecond, done, func(names []string) { rotated <- names })
+	writeTestFile(t, filepath.Join(dir, "secrets", "abc"), "rotated\n")
+	select {
+	case names := <-rotated:
+		assert.Equal(t, []string{"abc"}, names)
+	case <-time.After(time.Second):
+		assert.Fail(t, "The rotated credentials were not reported")
+	}
+}
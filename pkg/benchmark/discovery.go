@@ -0,0 +1,274 @@
+package benchmark
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discovery finds the Aura databases of the environment by listing the neo4jdatabases custom resources in Kubernetes,
+// and adds and removes jobs as databases appear and disappear. It is enabled by setting DISCOVERY_INTERVAL to the
+// number of seconds between listings, and ONLY_DATABASES_WITH_CHAOS_ENABLED=true limits it to databases that have
+// opted in to chaos testing. Discovered databases use the credentials in the credential store named after the dbid.
+//
+// Only jobs added by discovery are ever removed by it, together with their member jobs, so jobs added through the API or
+// configuration are kept. A discovered database with the same name as one of those jobs is left to that job.
+
+const (
+	serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+	chaosEnabledKey    = "neo4j.io/chaos-enabled"
+)
+
+type DiscoveredDatabase struct {
+	Dbid         string
+	Namespace    string
+	ChaosEnabled bool
+}
+
+type DatabaseLister interface {
+	ListDatabases() ([]DiscoveredDatabase, error)
+}
+
+// The subset of the neo4jdatabases custom resource used for discovery. Chaos testing is enabled by either the
+// 'neo4j.io/chaos-enabled' label or annotation, or the chaosEnabled field of the spec.
+type neo4jDatabaseResource struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		ChaosEnabled bool `json:"chaosEnabled"`
+	} `json:"spec"`
+}
+
+type neo4jDatabaseList struct {
+	Items []neo4jDatabaseResource `json:"items"`
+}
+
+func (r neo4jDatabaseResource) database() DiscoveredDatabase {
+	chaos := r.Spec.ChaosEnabled || r.Metadata.Labels[chaosEnabledKey] == "true" || r.Metadata.Annotations[chaosEnabledKey] == "true"
+	return DiscoveredDatabase{r.Metadata.Name, r.Metadata.Namespace, chaos}
+}
+
+func parseNeo4jDatabaseList(data []byte) ([]DiscoveredDatabase, error) {
+	list := neo4jDatabaseList{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid neo4jdatabases list: %v", err))
+	}
+	databases := []DiscoveredDatabase{}
+	for _, item := range list.Items {
+		databases = append(databases, item.database())
+	}
+	return databases, nil
+}
+
+// Lists and patches the neo4jdatabases custom resources using the Kubernetes API and the credentials of the service account
+type KubernetesClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewInClusterClient(apiVersion string) (*KubernetesClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("Not running in Kubernetes, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+	token, err := ioutil.ReadFile(serviceAccountPath + "/token")
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountPath + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("Invalid service account CA certificate")
+	}
+	client := &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	baseURL := fmt.Sprintf("https://%s:%s/apis/neo4j.io/%s", host, port, apiVersion)
+	return &KubernetesClient{baseURL, strings.TrimSpace(string(token)), client}, nil
+}
+
+func (k *KubernetesClient) ListDatabases() ([]DiscoveredDatabase, error) {
+	request, err := http.NewRequest(http.MethodGet, k.baseURL+"/neo4jdatabases", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+k.token)
+	request.Header.Set("Accept", contentTypeJSON)
+	response, err := k.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Failed to list neo4jdatabases: %s", response.Status))
+	}
+	return parseNeo4jDatabaseList(data)
+}
+
+func (k *KubernetesClient) PatchAnnotations(namespace string, name string, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/namespaces/%s/neo4jdatabases/%s", k.baseURL, namespace, name)
+	request, err := http.NewRequest(http.MethodPatch, path, bytes.NewReader(patch))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+k.token)
+	request.Header.Set(contentType, "application/merge-patch+json")
+	response, err := k.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Failed to patch neo4jdatabase '%s' in namespace '%s': %s", name, namespace, response.Status))
+	}
+	return nil
+}
+
+type DiscoveryStatus struct {
+	Interval         int      `json:"interval"`
+	OnlyChaosEnabled bool     `json:"onlyChaosEnabled"`
+	LastSync         string   `json:"lastSync,omitempty"`
+	Discovered       []string `json:"discovered"`
+	Errors           []string `json:"errors"`
+}
+
+type Discovery struct {
+	lister           DatabaseLister
+	interval         time.Duration
+	onlyChaosEnabled bool
+	lock             sync.Mutex
+	discovered       map[string]string // namespace of each discovered dbid
+	lastSync         time.Time
+	errors           []string
+}
+
+func NewDiscovery(lister DatabaseLister, interval time.Duration, onlyChaosEnabled bool) *Discovery {
+	return &Discovery{lister: lister, interval: interval, onlyChaosEnabled: onlyChaosEnabled, discovered: map[string]string{}, errors: []string{}}
+}
+
+// List the databases once, adding jobs for new databases and removing jobs for databases that have disappeared
+func (d *Discovery) Sync(s *Server, workload *Workload) error {
+	databases, err := d.lister.ListDatabases()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.lastSync = time.Now().UTC()
+	d.errors = []string{}
+	if err != nil {
+		log.Printf("Failed to discover databases: %v", err)
+		d.errors = append(d.errors, err.Error())
+		return err
+	}
+	current := map[string]bool{}
+	for _, database := range databases {
+		if d.onlyChaosEnabled && !database.ChaosEnabled {
+			continue
+		}
+		current[database.Dbid] = true
+		if _, ok := d.discovered[database.Dbid]; ok {
+			continue
+		}
+		if _, err := s.findJob(workload, database.Dbid); err == nil {
+			// added through the API or configuration, and added by discovery once that job is removed
+			continue
+		}
+		job, err := s.newNeo4jJob(JobSpec{Dbid: database.Dbid}, nil)
+		if err == nil {
+			err = workload.Add(job)
+		}
+		if err != nil {
+			// Not remembered as discovered, so that adding it is retried on the next sync
+			log.Printf("Failed to add discovered database '%s': %v", database.Dbid, err)
+			d.errors = append(d.errors, fmt.Sprintf("%s: %v", database.Dbid, err))
+			continue
+		}
+		log.Printf("Discovered database '%s'", database.Dbid)
+		d.discovered[database.Dbid] = database.Namespace
+	}
+	for dbid := range d.discovered {
+		if !current[dbid] {
+			log.Printf("Discovered database '%s' has disappeared", dbid)
+			if job, err := s.findJob(workload, dbid); err == nil {
+				workload.RemoveMembers(job)
+				workload.Remove(job)
+			}
+			delete(d.discovered, dbid)
+		}
+	}
+	return nil
+}
+
+// The namespace of each discovered database, for finding its resource again
+func (d *Discovery) Targets() map[string]string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	targets := map[string]string{}
+	for dbid, namespace := range d.discovered {
+		targets[dbid] = namespace
+	}
+	return targets
+}
+
+// Sync at the interval until the done channel is closed
+func (d *Discovery) Run(s *Server, workload *Workload, done chan struct{}) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.Sync(s, workload)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Discovery) Status() DiscoveryStatus {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	status := DiscoveryStatus{int(d.interval.Seconds()), d.onlyChaosEnabled, "", []string{}, append([]string{}, d.errors...)}
+	if !d.lastSync.IsZero() {
+		status.LastSync = d.lastSync.Format(time.RFC3339)
+	}
+	for dbid := range d.discovered {
+		status.Discovered = append(status.Discovered, dbid)
+	}
+	sort.Strings(status.Discovered)
+	return status
+}
+
+func (s *Server) apiDiscoveryHandler(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	if s.discovery == nil {
+		s.writeAPIError(writer, http.StatusNotFound, "Discovery is not enabled, DISCOVERY_INTERVAL is not set", nil)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, s.discovery.Status())
+}
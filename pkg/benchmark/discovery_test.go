@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type TestDatabaseLister struct {
+	databases []DiscoveredDatabase
+	err       error
+}
+
+func (l *TestDatabaseLister) ListDatabases() ([]DiscoveredDatabase, error) {
+	return l.databases, l.err
+}
+
+func Test_ParseNeo4jDatabaseList(t *testing.T) {
+	databases, err := parseNeo4jDatabaseList([]byte(`{"kind":"Neo4jDatabaseList","items":[
+		{"metadata":{"name":"abc","namespace":"neo4j-abc"},"spec":{"chaosEnabled":true}},
+		{"metadata":{"name":"xyz","namespace":"neo4j-xyz","labels":{"neo4j.io/chaos-enabled":"true"}}},
+		{"metadata":{"name":"123","namespace":"neo4j-123","annotations":{"neo4j.io/chaos-enabled":"false"}},"spec":{"size":"2GB"}}
+	]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []DiscoveredDatabase{{"abc", "neo4j-abc", true}, {"xyz", "neo4j-xyz", true}, {"123", "neo4j-123", false}}, databases)
+
+	_, err = parseNeo4jDatabaseList([]byte(`[]`))
+	assert.NotNil(t, err)
+}
+
+func Test_Discovery(t *testing.T) {
+	s, workload := mockServer(t)
+	lister := &TestDatabaseLister{databases: []DiscoveredDatabase{{"abc", "neo4j-abc", true}, {"123", "neo4j-123", false}}}
+	s.discovery = NewDiscovery(lister, time.Minute, true)
+	assert.Nil(t, workload.Add(NewNeo4jJob(*NewNeo4j("api", "bolt://api", "neo4j", "neo4j", "secret"))))
+	assert.Nil(t, workload.Add(NewNeo4jJob(*NewNeo4j("other", "bolt://other", "neo4j", "neo4j", "secret"))))
+
+	names := func() []string {
+		names := []string{}
+		for _, job := range workload.List() {
+			names = append(names, job.dbid)
+		}
+		return names
+	}
+
+	assert.Nil(t, s.discovery.Sync(&s, workload))
+	assert.Equal(t, []string{"abc", "api", "other"}, names(), "Only the chaos enabled database should have been added")
+	job, _ := s.findJob(workload, "abc")
+	assert.Equal(t, "", job.label)
+	assert.Equal(t, map[string]string{"abc": "neo4j-abc"}, s.discovery.Targets())
+	assert.Equal(t, "neo4j+s://abc-testenv.databases.neo4j.io", job.neo4j.neo4jAddress)
+
+	lister.databases = append(lister.databases, DiscoveredDatabase{"xyz", "neo4j-xyz", true}, DiscoveredDatabase{"other", "neo4j-other", true})
+	assert.Nil(t, s.discovery.Sync(&s, workload))
+	assert.Equal(t, []string{"abc", "api", "other", "xyz"}, names())
+	assert.Equal(t, []string{}, s.discovery.Status().Errors, "A database with the name of an existing job should be skipped")
+	abcMember := NewNeo4jJob(*NewNeo4j("abc~core-1", "bolt://core-1:7687", "neo4j", "neo4j", "secret"))
+	assert.Nil(t, workload.Add(abcMember))
+
+	lister.err = errors.New("connection refused")
+	assert.NotNil(t, s.discovery.Sync(&s, workload))
+	assert.Equal(t, []string{"abc", "abc~core-1", "api", "other", "xyz"}, names(), "Jobs should be kept when listing fails")
+
+	lister.err = nil
+	lister.databases = lister.databases[1:]
+	assert.Nil(t, s.discovery.Sync(&s, workload))
+	assert.Equal(t, []string{"api", "other", "xyz"}, names(), "Only the disappeared database and its members should have been removed")
+
+	lister.databases = lister.databases[:2]
+	assert.Nil(t, s.discovery.Sync(&s, workload))
+	assert.Equal(t, []string{"api", "other", "xyz"}, names(), "Jobs that were not discovered should never be removed by discovery")
+
+	request := httptest.NewRequest("GET", "/api/v1/discovery", nil)
+	responseRecorder := httptest.NewRecorder()
+	s.apiHandler(workload)(responseRecorder, request)
+	response := responseRecorder.Result()
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assertQuotedWildcardMatches(t, `{"interval":60,"onlyChaosEnabled":true,"lastSync":"***","discovered":["xyz"],"errors":[]}`, body)
+}
+
+func Test_DiscoveryRunStops(t *testing.T) {
+	s, workload := mockServer(t)
+	lister := &TestDatabaseLister{databases: []DiscoveredDatabase{}}
+	s.discovery = NewDiscovery(lister, time.Hour, false)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		s.discovery.Run(&s, workload, done)
+		close(finished)
+	}()
+	close(done)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Discovery did not stop")
+	}
+}
@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Endpoints for Kubernetes probes, which need no authentication. The liveness probe on /healthz fails when the read
+// loop of a running workload has stopped consuming messages, and the readiness probe on /readyz fails until the
+// configuration has been applied at startup. The /status endpoint gives more detail for people.
+
+const maxHeartbeatAge = 6 * heartbeatInterval
+
+// The state of a job for /status, where the last timestamps are zero if there were no results yet
+type JobState struct {
+	Dbid      string `json:"dbid"`
+	Running   bool   `json:"running"`
+	Read      int    `json:"read"`
+	Write     int    `json:"write"`
+	LastRead  int64  `json:"lastRead"`
+	LastWrite int64  `json:"lastWrite"`
+}
+
+type ServiceStatus struct {
+	Running         bool       `json:"running"`
+	Ready           bool       `json:"ready"`
+	HeartbeatAgeMs  int64      `json:"heartbeatAgeMs"`
+	Backlog         int        `json:"backlog"`
+	BacklogCapacity int        `json:"backlogCapacity"`
+	Jobs            []JobState `json:"jobs"`
+}
+
+func (s *Server) setReady() {
+	atomic.StoreInt32(s.ready, 1)
+}
+
+func (s *Server) isReady() bool {
+	return atomic.LoadInt32(s.ready) == 1
+}
+
+func lastTimestamp(result Result) int64 {
+	if len(result.timestamps) == 0 {
+		return 0
+	}
+	return result.timestamps[len(result.timestamps)-1]
+}
+
+func (s *Server) serviceStatus(workload *Workload) ServiceStatus {
+	status := ServiceStatus{workload.running, s.isReady(), workload.HeartbeatAge().Milliseconds(), 0, 0, []JobState{}}
+	if messages := workload.messages; messages != nil {
+		status.Backlog, status.BacklogCapacity = len(messages), cap(messages)
+	}
+	for _, client := range workload.List() {
+		read, write := workload.results.For(client.dbid, "read"), workload.results.For(client.dbid, "write")
+		status.Jobs = append(status.Jobs, JobState{client.dbid, client.isRunning(), len(read.durations), len(write.durations), lastTimestamp(read), lastTimestamp(write)})
+	}
+	return status
+}
+
+func writeProbe(writer http.ResponseWriter, status int, message string) {
+	writer.Header().Set(contentType, contentTypeText)
+	writer.WriteHeader(status)
+	fmt.Fprintln(writer, message)
+}
+
+func (s *Server) healthzHandler(workload *Workload) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if age := workload.HeartbeatAge(); age > maxHeartbeatAge {
+			writeProbe(writer, http.StatusServiceUnavailable, fmt.Sprintf("read loop has not run for %v", age.Round(time.Second)))
+		} else {
+			writeProbe(writer, http.StatusOK, "ok")
+		}
+	}
+}
+
+func (s *Server) readyzHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if !s.isReady() {
+			writeProbe(writer, http.StatusServiceUnavailable, "configuration is still being applied")
+		} else {
+			writeProbe(writer, http.StatusOK, "ok")
+		}
+	}
+}
+
+func (s *Server) statusHandler(workload *Workload) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		s.writeJSON(writer, http.StatusOK, s.serviceStatus(workload))
+	}
+}
@@ -0,0 +1,63 @@
+package benchmark
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Probes(t *testing.T) {
+	s, workload := mockServer(t)
+
+	recorder := httptest.NewRecorder()
+	s.readyzHandler()(recorder, mockRequest("/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "configuration is still being applied\n", recorder.Body.String())
+
+	s.setReady()
+	recorder = httptest.NewRecorder()
+	s.readyzHandler()(recorder, mockRequest("/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok\n", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.healthzHandler(workload)(recorder, mockRequest("/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok\n", recorder.Body.String())
+
+	// a running workload whose read loop has not run for a while is wedged
+	workload.running = true
+	workload.heartbeat = time.Now().Add(-2 * maxHeartbeatAge).UnixNano()
+	recorder = httptest.NewRecorder()
+	s.healthzHandler(workload)(recorder, mockRequest("/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "read loop has not run for 1m0s\n", recorder.Body.String())
+
+	workload.beat()
+	recorder = httptest.NewRecorder()
+	s.healthzHandler(workload)(recorder, mockRequest("/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func Test_Status(t *testing.T) {
+	s, workload := mockServer(t)
+	for _, dbid := range []string{"abc", "xyz"} {
+		err := workload.Add(NewNeo4jJob(*NewNeo4j(dbid, "neo4j+s://"+dbid+"-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+		assert.Nil(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		workload.results.Add("read", "abc", int64(10+i))
+	}
+	workload.results.Add("write", "abc", 100)
+	workload.messages = make(chan Message, 4)
+	workload.messages <- Message{}
+
+	recorder := httptest.NewRecorder()
+	s.statusHandler(workload)(recorder, mockRequest("/status", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"running":false,"ready":false,"heartbeatAgeMs":0,"backlog":1,"backlogCapacity":4,"jobs":[`+
+		`{"dbid":"abc","running":false,"read":3,"write":1,"lastRead":3,"lastWrite":4},`+
+		`{"dbid":"xyz","running":false,"read":0,"write":0,"lastRead":0,"lastWrite":0}]}`, recorder.Body.String())
+}
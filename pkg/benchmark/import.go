@@ -0,0 +1,156 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Bulk import of databases in the same table format as latency-benchmark.txt, with one database per line:
+//
+//     # name              password     uri
+//     TestDatabase_2G_1   4JRh7rW...   neo4j+s://143ea694-mypersonalenvironment.databases.neo4j.io
+//
+// or the equivalent JSON or YAML list of objects with 'name', 'password' and 'uri', which may also have 'username',
+// 'database' and 'credentials'. The name is kept as the label of the job, while the job itself is named after the
+// dbid in the Aura URI, or the host of any other URI.
+
+type TableEntry struct {
+	Name        string `json:"name" yaml:"name"`
+	Username    string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password    string `json:"password,omitempty" yaml:"password,omitempty"`
+	URI         string `json:"uri" yaml:"uri"`
+	Database    string `json:"database,omitempty" yaml:"database,omitempty"`
+	Credentials string `json:"credentials,omitempty" yaml:"credentials,omitempty"`
+	line        int
+	err         error
+}
+
+// The outcome of importing one entry, which never includes the password
+type ImportResult struct {
+	Line   int    `json:"line"`
+	Label  string `json:"label,omitempty"`
+	Dbid   string `json:"dbid,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// The dbid of an Aura URI like neo4j+s://143ea694-environment.databases.neo4j.io, or empty for any other URI
+func auraDbid(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || !strings.HasSuffix(parsed.Hostname(), ".databases.neo4j.io") {
+		return ""
+	}
+	host := parsed.Hostname()
+	if dash := strings.Index(host, "-"); dash > 0 && dash < strings.Index(host, ".") {
+		return host[:dash]
+	}
+	return ""
+}
+
+func (e TableEntry) spec() JobSpec {
+	spec := JobSpec{Dbid: auraDbid(e.URI), URI: e.URI, Label: e.Name, Database: e.Database, Credentials: e.Credentials}
+	if e.Password != "" {
+		spec.Username, spec.Password = e.Username, e.Password
+		if spec.Username == "" {
+			spec.Username = defaultUsername
+		}
+	}
+	return spec
+}
+
+func ParseTable(reader io.Reader) ([]TableEntry, error) {
+	entries := []TableEntry{}
+	scanner := bufio.NewScanner(reader)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			// Do not include the line itself, as it probably contains a password
+			err := newWorkloadError(ErrInvalid, "Invalid line %d: expected 'name password uri' but found %d fields", line, len(fields))
+			entries = append(entries, TableEntry{line: line, err: err})
+			continue
+		}
+		entries = append(entries, TableEntry{Name: fields[0], Password: fields[1], URI: fields[2], line: line})
+	}
+	return entries, scanner.Err()
+}
+
+func parseTableEntries(data []byte, unmarshal func([]byte, interface{}) error) ([]TableEntry, error) {
+	entries := []TableEntry{}
+	if err := unmarshal(data, &entries); err != nil {
+		return nil, newWorkloadError(ErrInvalid, "Invalid import: %v", err)
+	}
+	for i := range entries {
+		entries[i].line = i + 1
+	}
+	return entries, nil
+}
+
+// Parse the import in the format given by the content type, which defaults to the table format
+func ParseImport(mediaType string, reader io.Reader) ([]TableEntry, error) {
+	switch {
+	case strings.HasPrefix(mediaType, contentTypeJSON):
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return parseTableEntries(data, json.Unmarshal)
+	case strings.HasPrefix(mediaType, contentTypeYAML), strings.Contains(mediaType, "yaml"):
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return parseTableEntries(data, yaml.Unmarshal)
+	default:
+		return ParseTable(reader)
+	}
+}
+
+func (s *Server) importEntry(entry TableEntry, workload *Workload, request *http.Request) ImportResult {
+	result := ImportResult{Line: entry.line, Label: entry.Name, Status: http.StatusCreated}
+	err := entry.err
+	if err == nil {
+		var job *Neo4jJob
+		if job, err = s.newNeo4jJob(entry.spec(), request); err == nil {
+			result.Dbid = job.dbid
+			err = workload.Add(job)
+		}
+	}
+	if err != nil {
+		result.Status = statusOf(err)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (s *Server) apiImportHandler(writer http.ResponseWriter, request *http.Request, workload *Workload) {
+	if request.Method != http.MethodPost {
+		s.methodNotAllowed(writer, request, http.MethodPost)
+		return
+	}
+	if request.Body == nil {
+		s.handleAPIError(writer, "Failed to read import", newWorkloadError(ErrInvalid, "Missing request body"))
+		return
+	}
+	entries, err := ParseImport(request.Header.Get(contentType), request.Body)
+	if err != nil {
+		s.handleAPIError(writer, "Failed to read import", err)
+		return
+	}
+	results := []ImportResult{}
+	for _, entry := range entries {
+		results = append(results, s.importEntry(entry, workload, request))
+	}
+	s.writeJSON(writer, http.StatusOK, results)
+}
@@ -0,0 +1,81 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testTable = `
+# name password uri
+TestDatabase_2G_1  4JRh7rWxUZA9  neo4j+s://143ea694-testenv.databases.neo4j.io
+TestDatabase_2G_2  Uy7Pe59rKYlt
+Local              secret        bolt://localhost:7687
+`
+
+func Test_ParseTable(t *testing.T) {
+	entries, err := ParseTable(strings.NewReader(testTable))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, TableEntry{Name: "TestDatabase_2G_1", Password: "4JRh7rWxUZA9", URI: "neo4j+s://143ea694-testenv.databases.neo4j.io", line: 3}, entries[0])
+	assert.Equal(t, "Invalid line 4: expected 'name password uri' but found 2 fields", entries[1].err.Error())
+	assert.Equal(t, 5, entries[2].line)
+
+	assert.Equal(t, JobSpec{Dbid: "143ea694", URI: "neo4j+s://143ea694-testenv.databases.neo4j.io", Label: "TestDatabase_2G_1", Username: "neo4j", Password: "4JRh7rWxUZA9"}, entries[0].spec())
+	assert.Equal(t, JobSpec{URI: "bolt://localhost:7687", Label: "Local", Username: "neo4j", Password: "secret"}, entries[2].spec())
+}
+
+func Test_ParseImport(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		content   string
+		err       string
+	}{
+		{mediaType: "", content: "Local secret bolt://localhost:7687"},
+		{mediaType: "text/plain; charset=utf-8", content: "Local secret bolt://localhost:7687"},
+		{mediaType: contentTypeJSON, content: `[{"name":"Local","password":"secret","uri":"bolt://localhost:7687"}]`},
+		{mediaType: contentTypeYAML, content: "- name: Local\n  password: secret\n  uri: bolt://localhost:7687\n"},
+		{mediaType: "text/yaml", content: "- {name: Local, password: secret, uri: 'bolt://localhost:7687'}"},
+		{mediaType: contentTypeJSON, content: `{"name":"Local"}`, err: "Invalid import: json: cannot unmarshal object into Go value of type []benchmark.TableEntry"},
+	}
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d %s", index+1, data.mediaType), func(t *testing.T) {
+			entries, err := ParseImport(data.mediaType, strings.NewReader(data.content))
+			if data.err != "" {
+				assert.NotNil(t, err)
+				assert.Equal(t, data.err, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, []TableEntry{{Name: "Local", Password: "secret", URI: "bolt://localhost:7687", line: 1}}, entries)
+		})
+	}
+}
+
+func Test_Import(t *testing.T) {
+	s, workload := mockServer(t)
+	handler := s.apiHandler(workload)
+
+	request := httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(testTable+"Local again bolt://localhost:7687\n"))
+	request.SetBasicAuth("ignored", "secret")
+	responseRecorder := httptest.NewRecorder()
+	handler(responseRecorder, request)
+	response := responseRecorder.Result()
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, `[`+
+		`{"line":3,"label":"TestDatabase_2G_1","dbid":"143ea694","status":201},`+
+		`{"line":4,"status":400,"error":"Invalid line 4: expected 'name password uri' but found 2 fields"},`+
+		`{"line":5,"label":"Local","dbid":"localhost","status":201},`+
+		`{"line":6,"label":"Local","dbid":"localhost","status":409,"error":"Client for database 'localhost' already exists"}]`, string(body))
+	assert.NotContains(t, string(body), "4JRh7rWxUZA9")
+
+	job, err := s.findJob(workload, "143ea694")
+	assert.Nil(t, err)
+	assert.Equal(t, "TestDatabase_2G_1", makeJobStatus(job, workload).Label)
+	assert.Equal(t, "neo4j+s://143ea694-testenv.databases.neo4j.io", job.neo4j.neo4jAddress)
+}
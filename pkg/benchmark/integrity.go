@@ -0,0 +1,148 @@
+package benchmark
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Each write of a job increments the counter of its ClientBenchmark node and returns the new value. Other jobs on the
+// same database may increment it too, so values can be skipped, but a value returned twice, a value lower than the one
+// before, or a counter that has not grown by at least the number of acknowledged writes means that acknowledged
+// writes were lost, for example after a failover. These integrity violations are recorded as 'integrity:error' events.
+
+const integrityError = "integrity:error"
+
+type IntegrityViolation struct {
+	Timestamp int64  `json:"timestamp"`
+	Kind      string `json:"kind"` // lost-update, duplicate or regression
+	Value     int64  `json:"value"`
+	Expected  int64  `json:"expected"` // the lowest value the counter should have had
+	Message   string `json:"message"`
+}
+
+type IntegrityStatus struct {
+	Dbid         string               `json:"dbid"`
+	Acknowledged int64                `json:"acknowledged"`
+	First        int64                `json:"first"` // the first value returned by a write, or zero if there was none
+	Last         int64                `json:"last"`
+	Lost         int64                `json:"lost"` // acknowledged writes missing from the counter
+	Violations   []IntegrityViolation `json:"violations"`
+}
+
+// The counter values returned by the writes of one job
+type Integrity struct {
+	lock         sync.Mutex
+	acknowledged int64
+	base         int64 // the value of the counter before the first write
+	last         int64
+	lost         int64
+	seen         map[int64]bool
+	violations   []IntegrityViolation
+}
+
+func NewIntegrity() *Integrity {
+	return &Integrity{seen: map[int64]bool{}, violations: []IntegrityViolation{}}
+}
+
+// Check the value returned by an acknowledged write, returning any violations it reveals, which are not timestamped
+// or recorded yet
+func (i *Integrity) Check(value int64) []IntegrityViolation {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.acknowledged == 0 {
+		i.base = value - 1
+		i.last = i.base
+	}
+	i.acknowledged++
+	violations := []IntegrityViolation{}
+	if i.seen[value] {
+		violations = append(violations, IntegrityViolation{0, "duplicate", value, i.last + 1, fmt.Sprintf("counter value %d was returned before", value)})
+	} else if value < i.last {
+		violations = append(violations, IntegrityViolation{0, "regression", value, i.last + 1, fmt.Sprintf("counter went back from %d to %d", i.last, value)})
+	}
+	expected := i.base + i.acknowledged
+	if lost := expected - value; lost > i.lost {
+		violations = append(violations, IntegrityViolation{0, "lost-update", value, expected, fmt.Sprintf("counter is %d after %d acknowledged writes from %d, %d lost", value, i.acknowledged, i.base, lost)})
+		i.lost = lost
+	}
+	i.seen[value] = true
+	i.last = value
+	return violations
+}
+
+func (i *Integrity) add(violation IntegrityViolation) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.violations = append(i.violations, violation)
+}
+
+func (i *Integrity) Status(dbid string) IntegrityStatus {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	status := IntegrityStatus{dbid, i.acknowledged, 0, 0, i.lost, append([]IntegrityViolation{}, i.violations...)}
+	if i.acknowledged > 0 {
+		status.First, status.Last = i.base+1, i.last
+	}
+	return status
+}
+
+// The integrity of the counter of the job, which is kept when the job is removed or replaced, like its results, unless
+// the replacement writes to another counter
+func (w *Workload) IntegrityFor(dbid string) *Integrity {
+	w.integrityLock.Lock()
+	defer w.integrityLock.Unlock()
+	integrity, ok := w.integrity[dbid]
+	if !ok {
+		integrity = NewIntegrity()
+		w.integrity[dbid] = integrity
+	}
+	return integrity
+}
+
+// Forget the counter values of the job, when it is replaced by one for another address or database, whose counter
+// has nothing to do with the previous one
+func (w *Workload) resetIntegrity(dbid string) {
+	w.integrityLock.Lock()
+	defer w.integrityLock.Unlock()
+	delete(w.integrity, dbid)
+}
+
+func (w *Workload) clearIntegrity() {
+	w.integrityLock.Lock()
+	defer w.integrityLock.Unlock()
+	w.integrity = map[string]*Integrity{}
+}
+
+func (w *Workload) checkCounter(dbid string, value int64) {
+	integrity := w.IntegrityFor(dbid)
+	for _, violation := range integrity.Check(value) {
+		violation.Timestamp = w.addEvent(dbid, integrityError, fmt.Sprintf("%s: %s", violation.Kind, violation.Message))
+		integrity.add(violation)
+	}
+}
+
+// The acknowledged writes and violations of each job with any writes
+func (w *Workload) IntegritySummary() *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "acknowledged", "first", "last", "lost", "duplicates", "regressions"})
+	for _, client := range w.List() {
+		status := w.IntegrityFor(client.dbid).Status(client.dbid)
+		if status.Acknowledged == 0 {
+			continue
+		}
+		counts := map[string]int{}
+		for _, violation := range status.Violations {
+			counts[violation.Kind]++
+		}
+		result.add([]interface{}{client.dbid, status.Acknowledged, status.First, status.Last, status.Lost, counts["duplicate"], counts["regression"]})
+	}
+	return result
+}
+
+func (s *Server) apiIntegrityHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, workload.IntegrityFor(job.dbid).Status(job.dbid))
+}
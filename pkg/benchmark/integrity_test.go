@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_IntegrityCheck(t *testing.T) {
+	tests := []struct {
+		values   []int64
+		expected []string
+		lost     int64
+	}{
+		{[]int64{5, 6, 7, 8}, []string{}, 0},
+		{[]int64{5, 7, 10}, []string{}, 0},
+		{[]int64{5, 6, 7, 7}, []string{"duplicate", "lost-update"}, 1},
+		{[]int64{5, 6, 7, 6, 7, 8}, []string{"duplicate", "lost-update", "duplicate"}, 2},
+		{[]int64{5, 6, 7, 3}, []string{"regression", "lost-update"}, 5},
+		{[]int64{5, 6, 9, 8}, []string{"regression"}, 0},
+	}
+	for _, test := range tests {
+		integrity := NewIntegrity()
+		kinds := []string{}
+		for _, value := range test.values {
+			for _, violation := range integrity.Check(value) {
+				kinds = append(kinds, violation.Kind)
+			}
+		}
+		assert.Equal(t, test.expected, kinds, "%v", test.values)
+		status := integrity.Status("abc")
+		assert.Equal(t, int64(len(test.values)), status.Acknowledged, "%v", test.values)
+		assert.Equal(t, test.values[0], status.First)
+		assert.Equal(t, test.values[len(test.values)-1], status.Last)
+		assert.Equal(t, test.lost, status.Lost, "%v", test.values)
+	}
+}
+
+func Test_Integrity(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := mockPublishedWorkload(t)
+	for _, value := range []int64{41, 42, 43, 42} {
+		workload.record(Message{verb: "counter", dbid: "abc", value: value})
+	}
+
+	recorder := httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/abc/integrity", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"dbid":"abc","acknowledged":4,"first":41,"last":42,"lost":2,"violations":[`+
+		`{"timestamp":5,"kind":"duplicate","value":42,"expected":44,"message":"counter value 42 was returned before"},`+
+		`{"timestamp":6,"kind":"lost-update","value":42,"expected":44,"message":"counter is 42 after 4 acknowledged writes from 40, 2 lost"}]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/xyz/integrity", nil))
+	assert.Equal(t, `{"dbid":"xyz","acknowledged":0,"first":0,"last":0,"lost":0,"violations":[]}`, recorder.Body.String())
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]interface{}{{"abc", int64(4), int64(41), int64(42), int64(2), 1, 0}}, report.Integrity.Rows)
+	var markdown strings.Builder
+	assert.Nil(t, report.WriteMarkdown(&markdown))
+	assert.Contains(t, markdown.String(), "| abc | integrity:error | 2 |")
+}
+
+func Test_IntegrityReplace(t *testing.T) {
+	workload := NewWorkload(&TestSessionMaker{})
+	assert.Nil(t, workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))))
+	workload.record(Message{verb: "counter", dbid: "abc", value: 41})
+
+	assert.Nil(t, workload.Replace(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "other"))))
+	assert.Equal(t, int64(1), workload.IntegrityFor("abc").Status("abc").Acknowledged)
+
+	assert.Nil(t, workload.Replace(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "other", "neo4j", "other"))))
+	assert.Equal(t, int64(0), workload.IntegrityFor("abc").Status("abc").Acknowledged)
+	workload.record(Message{verb: "counter", dbid: "abc", value: 7})
+
+	assert.Nil(t, workload.Replace(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://xyz-testenv.databases.neo4j.io", "other", "neo4j", "other"))))
+	status := workload.IntegrityFor("abc").Status("abc")
+	assert.Equal(t, int64(0), status.Acknowledged)
+	assert.Equal(t, []IntegrityViolation{}, status.Violations)
+}
@@ -0,0 +1,193 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Jobs with a lag rate regularly write a marker with the current time through their routed connection, which goes to
+// the leader, and then poll every other member of the routing table of their database with a direct connection until
+// the marker is visible there. The lag of a member is the time from the commit of the marker until the end of the
+// first poll that saw it, so it includes one round trip and is at most lagPollInterval too long.
+
+const lagPollInterval = 10 * time.Millisecond
+
+// Members which have not caught up with a marker after this long are reported as an error
+const maxReplicationLag = 10 * time.Second
+
+// Polling ends early when the job is stopped, which is neither a lag nor an error of the member
+var errLagStopped = errors.New("Stopped before the marker was visible")
+
+// Poll the member until the marker is visible, returning the member as the server reported it
+func (n *Neo4jJob) pollMarker(runner QuerySession, address string, marker int64, written time.Time) (Member, time.Duration, error) {
+	query := "MATCH (m:ClientBenchmarkMarker) RETURN m.written"
+	for {
+		result, err := runner.RunCypherQuery(neo4j.AccessModeRead, query)
+		lag := time.Since(written)
+		if err != nil {
+			return Member{address, ""}, lag, err
+		}
+		member := result.server
+		if member.Address == "" {
+			member = Member{address, ""}
+		}
+		if len(result.Rows) == 1 {
+			if seen, ok := result.Rows[0][0].(int64); ok && seen >= marker {
+				return member, lag, nil
+			}
+		}
+		if lag > maxReplicationLag {
+			return member, lag, errors.New(fmt.Sprintf("Marker %d not visible after %v", marker, maxReplicationLag))
+		}
+		if !n.sleep(lagPollInterval) {
+			return member, lag, errLagStopped
+		}
+	}
+}
+
+// The direct sessions to the members, which are opened when a member is first seen and closed when it leaves the
+// routing table or with the workload
+type lagSessions struct {
+	job      *Neo4jJob
+	maker    SessionMaker
+	sessions map[string]QuerySession
+}
+
+func (s *lagSessions) get(member RoutingMember) (QuerySession, error) {
+	if session, ok := s.sessions[member.Address]; ok {
+		return session, nil
+	}
+	address, err := directAddress(s.job.neo4j.neo4jAddress, member.Address)
+	if err != nil {
+		return nil, err
+	}
+	direct := s.job.neo4j
+	direct.dbid = member.Job
+	direct.neo4jAddress = address
+	session, err := s.maker.NewQuerySession(direct, neo4j.AccessModeRead)
+	if err != nil {
+		return nil, err
+	}
+	s.sessions[member.Address] = session
+	return session, nil
+}
+
+// Close the sessions to members which are no longer in the routing table
+func (s *lagSessions) retain(members []RoutingMember) {
+	present := map[string]bool{}
+	for _, member := range members {
+		present[member.Address] = true
+	}
+	for address, session := range s.sessions {
+		if !present[address] {
+			session.Close()
+			delete(s.sessions, address)
+		}
+	}
+}
+
+func (s *lagSessions) Close() {
+	for _, session := range s.sessions {
+		session.Close()
+	}
+}
+
+// Write one marker on the leader and wait for it on all other members, sending the lag of each to the channel
+func (n *Neo4jJob) measureLag(ch chan Message, writer QuerySession, sessions *lagSessions) error {
+	members, err := n.routingMembers(writer)
+	if err != nil {
+		return err
+	}
+	sessions.retain(members)
+	marker := time.Now().UnixNano()
+	result, err := writer.RunCypherQuery(neo4j.AccessModeWrite, fmt.Sprintf("MERGE (m:ClientBenchmarkMarker) SET m.written = %d RETURN m.written", marker))
+	if err != nil {
+		return err
+	}
+	written := time.Now()
+	leader := result.server.Address
+	var wait sync.WaitGroup
+	for _, member := range members {
+		if member.Address == leader || member.hasRole("WRITE") {
+			continue
+		}
+		runner, err := sessions.get(member)
+		if err != nil {
+			ch <- Message{verb: "lag:error", dbid: n.dbid, value: -1, message: fmt.Sprintf("%s: %v", member.Address, err), server: Member{member.Address, ""}}
+			continue
+		}
+		wait.Add(1)
+		go func(address string, runner QuerySession) {
+			defer wait.Done()
+			served, lag, err := n.pollMarker(runner, address, marker, written)
+			if err == errLagStopped {
+				return
+			} else if err != nil {
+				ch <- Message{verb: "lag:error", dbid: n.dbid, value: -1, message: fmt.Sprintf("%s: %v", address, err), server: served}
+			} else {
+				ch <- Message{verb: "lag", dbid: n.dbid, value: lag.Milliseconds(), server: served}
+			}
+		}(member.Address, runner)
+	}
+	wait.Wait()
+	return nil
+}
+
+func (n *Neo4jJob) runLagWorkload(ch chan Message, maker SessionMaker, interval time.Duration) {
+	writer, err := maker.NewQuerySession(n.neo4j, neo4j.AccessModeWrite)
+	if err != nil {
+		log.Printf("Failed to create runner for lag workload against '%s': %v", n.dbid, err)
+		ch <- Message{verb: "lag:error", dbid: n.dbid, value: -1, message: err.Error()}
+		return
+	}
+	defer writer.Close()
+	sessions := &lagSessions{n, maker, map[string]QuerySession{}}
+	defer sessions.Close()
+	log.Printf("Starting lag workload against '%s' every %v", n.dbid, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for n.tick(ticker) {
+		if err := n.measureLag(ch, writer, sessions); err != nil {
+			log.Printf("Failed to measure replication lag of '%s': %v", n.dbid, err)
+			ch <- Message{verb: "lag:error", dbid: n.dbid, value: -1, message: err.Error()}
+		}
+	}
+	log.Printf("Finishing lag workload against '%s'", n.dbid)
+}
+
+// Percentiles of the replication lag to each member, for one job or for all jobs if dbid is empty
+func (w *Workload) LagSummary(dbid string) *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "server", "count", "min", "p50", "p90", "p99", "max"})
+	for _, client := range w.List() {
+		if dbid != "" && client.dbid != dbid {
+			continue
+		}
+		byMember := w.results.ByMember(w.results.For(client.dbid, "lag"))
+		members := []Member{}
+		for member := range byMember {
+			members = append(members, member)
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].Address < members[j].Address
+		})
+		for _, member := range members {
+			lags := byMember[member]
+			result.add([]interface{}{client.dbid, member.Address, len(lags), percentile(lags, 0), percentile(lags, 50), percentile(lags, 90), percentile(lags, 99), percentile(lags, 100)})
+		}
+	}
+	return result
+}
+
+func (s *Server) apiLagHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, workload.LagSummary(job.dbid))
+}
@@ -0,0 +1,138 @@
+package benchmark
+
+import (
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Writes markers through the routed uri and makes them visible on each direct uri after the given number of polls
+type TestLagSessionMaker struct {
+	TestRoutingSessionMaker
+	lock    sync.Mutex
+	marker  int64
+	polls   map[string]int
+	delayed map[string]int
+}
+
+type TestLagSession struct {
+	TestRoutingSession
+	maker   *TestLagSessionMaker
+	address string
+}
+
+func (m *TestLagSessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
+	return &TestLagSession{maker: m, address: n.neo4jAddress}, nil
+}
+
+func (r *TestLagSession) RunCypherQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	r.maker.lock.Lock()
+	defer r.maker.lock.Unlock()
+	switch {
+	case strings.HasPrefix(query, "MERGE (m:ClientBenchmarkMarker)"):
+		fmt.Sscanf(query, "MERGE (m:ClientBenchmarkMarker) SET m.written = %d", &r.maker.marker)
+		result := NewNeo4jResult([]string{"m.written"})
+		result.add([]interface{}{r.maker.marker})
+		result.server = Member{"core-1:7687", "Neo4j/4.2.0"}
+		return result, nil
+	case strings.HasPrefix(query, "MATCH (m:ClientBenchmarkMarker)"):
+		r.maker.polls[r.address]++
+		result := NewNeo4jResult([]string{"m.written"})
+		if r.maker.polls[r.address] > r.maker.delayed[r.address] {
+			result.add([]interface{}{r.maker.marker})
+		}
+		result.server = Member{strings.TrimPrefix(r.address, "bolt+s://"), "Neo4j/4.2.0"}
+		return result, nil
+	default:
+		return r.TestRoutingSession.RunCypherQuery(accessMode, query)
+	}
+}
+
+func Test_MeasureLag(t *testing.T) {
+	s, _ := mockServer(t)
+	maker := &TestLagSessionMaker{polls: map[string]int{}, delayed: map[string]int{"bolt+s://replica-1:7687": 3}}
+	workload := NewWorkload(maker)
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	assert.Nil(t, workload.Add(job))
+	writer, _ := maker.NewQuerySession(job.neo4j, neo4j.AccessModeWrite)
+	sessions := &lagSessions{job, maker, map[string]QuerySession{}}
+
+	ch := make(chan Message, 10)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, job.measureLag(ch, writer, sessions))
+		maker.polls = map[string]int{}
+	}
+	close(ch)
+	for msg := range ch {
+		assert.Equal(t, "lag", msg.verb)
+		workload.record(msg)
+	}
+	assert.Equal(t, 2, len(sessions.sessions))
+	assert.Equal(t, map[string]int{}, maker.polls)
+
+	summary := workload.LagSummary("abc")
+	assert.Equal(t, []string{"dbid", "server", "count", "min", "p50", "p90", "p99", "max"}, summary.Header)
+	assert.Equal(t, 2, len(summary.Rows))
+	assert.Equal(t, []interface{}{"abc", "core-2:7687", 2}, summary.Rows[0][:3])
+	assert.Equal(t, []interface{}{"abc", "replica-1:7687", 2}, summary.Rows[1][:3])
+	assert.True(t, summary.Rows[1][3].(int64) >= int64(3*lagPollInterval.Milliseconds()))
+
+	recorder := httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/abc/lag", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"Header":["dbid","server","count","min","p50","p90","p99","max"]`)
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(report.Lag.Rows))
+}
+
+// Remembers whether it was closed
+type TestClosingSession struct {
+	TestQuerySession
+	closed bool
+}
+
+func (r *TestClosingSession) Close() error {
+	r.closed = true
+	return nil
+}
+
+func Test_LagSessionsRetain(t *testing.T) {
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	core, replica := &TestClosingSession{}, &TestClosingSession{}
+	sessions := &lagSessions{job, &TestLagSessionMaker{}, map[string]QuerySession{"core-2:7687": core, "replica-1:7687": replica}}
+
+	sessions.retain([]RoutingMember{{Address: "core-1:7687"}, {Address: "core-2:7687"}})
+	assert.Equal(t, map[string]QuerySession{"core-2:7687": core}, sessions.sessions)
+	assert.False(t, core.closed)
+	assert.True(t, replica.closed)
+}
+
+func Test_MeasureLagStops(t *testing.T) {
+	maker := &TestLagSessionMaker{polls: map[string]int{}, delayed: map[string]int{"bolt+s://replica-1:7687": 1000000}}
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	writer, _ := maker.NewQuerySession(job.neo4j, neo4j.AccessModeWrite)
+	sessions := &lagSessions{job, maker, map[string]QuerySession{}}
+	close(job.done)
+
+	ch := make(chan Message, 10)
+	started := time.Now()
+	assert.Nil(t, job.measureLag(ch, writer, sessions))
+	assert.True(t, time.Since(started) < time.Second)
+	close(ch)
+	messages := []Message{}
+	for msg := range ch {
+		messages = append(messages, msg)
+	}
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "lag", messages[0].verb)
+	assert.Equal(t, Member{"core-2:7687", "Neo4j/4.2.0"}, messages[0].server)
+	assert.Equal(t, 1, maker.polls["bolt+s://replica-1:7687"])
+}
@@ -0,0 +1,106 @@
+package benchmark
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// What happened to the writes of a job when the leader of its database switched. All times are in seconds, like the
+// timestamps of the results, and are -1 if there was no successful write after the switch yet. The unavailability is
+// the time from the last successful write before the switch to the first one after it, and the elevation compares
+// the p99 write latency in the window after that first write with the window before the switch.
+type LeaderSwitchMetrics struct {
+	LeaderSwitch
+	Unavailable      int64   `json:"unavailable"`
+	TimeToFirstWrite int64   `json:"timeToFirstWrite"`
+	FailedWrites     int     `json:"failedWrites"`
+	BaselineP99      int64   `json:"baselineP99"`
+	AfterP99         int64   `json:"afterP99"`
+	Elevation        float64 `json:"elevation"` // ratio of AfterP99 to BaselineP99, zero if either is unknown
+}
+
+func durationsBetween(result Result, from int64, to int64) []int64 {
+	durations := []int64{}
+	for i, timestamp := range result.timestamps {
+		if timestamp >= from && timestamp < to {
+			durations = append(durations, result.durations[i])
+		}
+	}
+	return durations
+}
+
+func switchMetrics(leaderSwitch LeaderSwitch, writes Result, failures []int64, window int64) LeaderSwitchMetrics {
+	metrics := LeaderSwitchMetrics{leaderSwitch, -1, -1, 0, 0, 0, 0}
+	lastBefore, firstAfter := leaderSwitch.Timestamp, int64(-1)
+	for _, timestamp := range writes.timestamps {
+		if timestamp < leaderSwitch.Timestamp {
+			lastBefore = timestamp
+		} else if firstAfter < 0 {
+			firstAfter = timestamp
+		}
+	}
+	metrics.BaselineP99 = percentile(durationsBetween(writes, leaderSwitch.Timestamp-window, leaderSwitch.Timestamp), 99)
+	if firstAfter >= 0 {
+		metrics.Unavailable = firstAfter - lastBefore
+		metrics.TimeToFirstWrite = firstAfter - leaderSwitch.Timestamp
+		metrics.AfterP99 = percentile(durationsBetween(writes, firstAfter, firstAfter+window), 99)
+	}
+	if metrics.BaselineP99 > 0 && metrics.AfterP99 > 0 {
+		metrics.Elevation = float64(metrics.AfterP99) / float64(metrics.BaselineP99)
+	}
+	for _, timestamp := range failures {
+		if timestamp >= lastBefore && (firstAfter < 0 || timestamp <= firstAfter) {
+			metrics.FailedWrites++
+		}
+	}
+	return metrics
+}
+
+// The write metrics of each switch of the leader of the database of the job, for windows of the number of seconds
+func (w *Workload) LeaderSwitchMetrics(client *Neo4jJob, window int64) []LeaderSwitchMetrics {
+	writes := w.results.For(client.dbid, "write")
+	failures := []int64{}
+	for _, event := range w.Events() {
+		if event.dbid == client.dbid && event.kind == "write:error" {
+			failures = append(failures, event.timestamp)
+		}
+	}
+	metrics := []LeaderSwitchMetrics{}
+	for _, leaderSwitch := range w.TopologyFor(client.dbid).Switches(client.neo4j.database) {
+		metrics = append(metrics, switchMetrics(leaderSwitch, writes, failures, window))
+	}
+	return metrics
+}
+
+// The leader switches of all jobs with their write metrics, as a table for the report
+func (w *Workload) LeaderSwitchSummary(window int64) *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "timestamp", "database", "from", "to", "unavailable", "time to first write", "failed writes", "baseline p99", "after p99", "elevation"})
+	for _, client := range w.List() {
+		for _, m := range w.LeaderSwitchMetrics(client, window) {
+			result.add([]interface{}{client.dbid, m.Timestamp, m.Database, m.From, m.To, m.Unavailable, m.TimeToFirstWrite, m.FailedWrites, m.BaselineP99, m.AfterP99, fmt.Sprintf("%.2f", m.Elevation)})
+		}
+	}
+	return result
+}
+
+// Annotations for the leader switches of the job, to mark them on charts of its latency
+func (w *Workload) leaderSwitchAnnotations(client *Neo4jJob) []ChartAnnotation {
+	annotations := []ChartAnnotation{}
+	for _, leaderSwitch := range w.TopologyFor(client.dbid).Switches(client.neo4j.database) {
+		annotations = append(annotations, ChartAnnotation{leaderSwitch.Timestamp, "leader " + leaderSwitch.To})
+	}
+	return annotations
+}
+
+func (s *Server) apiLeaderSwitchesHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	window, err := queryInt(request, "window", 10)
+	if err != nil {
+		s.writeAPIError(writer, http.StatusBadRequest, "Failed to parse window as integer", err)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, workload.LeaderSwitchMetrics(job, window))
+}
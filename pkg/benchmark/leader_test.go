@@ -0,0 +1,82 @@
+package benchmark
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_LeaderSwitches(t *testing.T) {
+	topology := NewTopology()
+	system := func(role string) []interface{} {
+		return []interface{}{"system", "core-1:7687", role, "online", "online", "", false, false}
+	}
+	tests := []struct {
+		result   *Neo4jResult
+		expected []LeaderSwitch
+	}{
+		{showDatabases(member("core-1:7687", "leader", "online"), member("core-2:7687", "follower", "online"), system("leader")), []LeaderSwitch{}},
+		{showDatabases(member("core-1:7687", "follower", "online"), member("core-2:7687", "follower", "online"), system("leader")), []LeaderSwitch{}},
+		{showDatabases(member("core-1:7687", "follower", "online"), member("core-2:7687", "leader", "online"), system("follower")), []LeaderSwitch{{2, 3, "neo4j", "core-1:7687", "core-2:7687"}}},
+		{showDatabases(member("core-1:7687", "follower", "online"), member("core-2:7687", "leader", "online"), system("follower")), []LeaderSwitch{}},
+		{showDatabases(member("core-1:7687", "leader", "online"), member("core-2:7687", "follower", "online"), system("leader")), []LeaderSwitch{{5, 5, "neo4j", "core-2:7687", "core-1:7687"}}},
+	}
+	for i, test := range tests {
+		_, switches, err := topology.Update(int64(i+1), test.result)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, switches, "poll %d", i+1)
+	}
+	assert.Equal(t, []LeaderSwitch{{2, 3, "neo4j", "core-1:7687", "core-2:7687"}, {5, 5, "neo4j", "core-2:7687", "core-1:7687"}}, topology.Switches("neo4j"))
+	assert.Equal(t, 2, len(topology.Switches("")))
+	assert.Equal(t, []LeaderSwitch{}, topology.Switches("system"))
+}
+
+func Test_SwitchMetrics(t *testing.T) {
+	leaderSwitch := LeaderSwitch{5, 6, "neo4j", "core-1:7687", "core-2:7687"}
+	tests := []struct {
+		writes   Result
+		failures []int64
+		expected LeaderSwitchMetrics
+	}{
+		{Result{client: "abc", verb: "write", timestamps: []int64{1, 2, 3, 4, 8, 9, 10}, durations: []int64{10, 10, 10, 12, 50, 40, 11}}, []int64{5, 6, 7},
+			LeaderSwitchMetrics{leaderSwitch, 4, 3, 3, 12, 50, 50.0 / 12}},
+		{Result{client: "abc", verb: "write", timestamps: []int64{1, 2}, durations: []int64{10, 20}}, []int64{5, 6},
+			LeaderSwitchMetrics{leaderSwitch, -1, -1, 2, 20, 0, 0}},
+		{Result{client: "abc", verb: "write", timestamps: []int64{5, 6}, durations: []int64{30, 20}}, []int64{},
+			LeaderSwitchMetrics{leaderSwitch, 0, 0, 0, 0, 30, 0}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, switchMetrics(leaderSwitch, test.writes, test.failures, 3))
+	}
+}
+
+func Test_LeaderSwitchMetrics(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := mockPublishedWorkload(t)
+	topology := workload.TopologyFor("abc")
+	_, _, err := topology.Update(9, showDatabases(member("core-1:7687", "leader", "online"), member("core-2:7687", "follower", "online")))
+	assert.Nil(t, err)
+	_, _, err = topology.Update(11, showDatabases(member("core-1:7687", "follower", "online"), member("core-2:7687", "leader", "online")))
+	assert.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/abc/leader-switches", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `[{"timestamp":11,"detected":11,"database":"neo4j","from":"core-1:7687","to":"core-2:7687",`+
+		`"unavailable":2,"timeToFirstWrite":1,"failedWrites":0,"baselineP99":104,"afterP99":109,"elevation":1.0480769230769231}]`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/xyz/leader-switches", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `[]`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.chartsHandler(workload)(recorder, mockRequest("/charts/abc/write", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), ">leader core-2:7687</text>")
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]interface{}{{"abc", int64(11), "neo4j", "core-1:7687", "core-2:7687", int64(2), int64(1), 0, int64(104), int64(109), "1.05"}}, report.Switches.Rows)
+}
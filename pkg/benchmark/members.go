@@ -0,0 +1,242 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The summary of each query tells which member of the cluster served it, so that the latencies can be broken down
+// per member, for example to see whether slow reads were served by a follower that was catching up.
+
+// A member of the cluster, with the agent string of the server, like Neo4j/4.2.0
+type Member struct {
+	Address string `json:"address"`
+	Agent   string `json:"agent"`
+}
+
+// Percentiles of the durations of the job for each member that served its queries, where queries for which the
+// member is not known are counted with an empty address
+func (w *Workload) MemberSummary(dbid string, verb string) (*Neo4jResult, error) {
+	if verb != "read" && verb != "write" {
+		return nil, newWorkloadError(ErrInvalid, "Invalid result verb: %s", verb)
+	}
+	byMember := w.results.ByMember(w.results.For(dbid, verb))
+	members := []Member{}
+	for member := range byMember {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Address != members[j].Address {
+			return members[i].Address < members[j].Address
+		}
+		return members[i].Agent < members[j].Agent
+	})
+	result := NewNeo4jResult([]string{"server", "agent", "count", "min", "p50", "p90", "p99", "max"})
+	for _, member := range members {
+		durations := byMember[member]
+		result.add([]interface{}{member.Address, member.Agent, len(durations), percentile(durations, 0), percentile(durations, 50), percentile(durations, 90), percentile(durations, 99), percentile(durations, 100)})
+	}
+	return result, nil
+}
+
+// Instead of letting the driver route queries, a job can be split into direct jobs against each member in the routing
+// table of its database, named '<job>~<address>', which connect with bolt and so always use that member. Members that
+// cannot take writes, like followers and read replicas, only run the reads of the workload.
+
+const memberSeparator = "~"
+
+// A member of the cluster as listed in the routing table, with its roles WRITE, READ and ROUTE
+type RoutingMember struct {
+	Address string   `json:"address"`
+	Roles   []string `json:"roles"`
+	Job     string   `json:"job"` // the name of the direct job for the member
+}
+
+func (m RoutingMember) hasRole(role string) bool {
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func memberJobName(dbid string, address string) string {
+	return dbid + memberSeparator + address
+}
+
+// The uri for a direct connection to the member, using the bolt scheme with the same encryption as the routed uri
+func directAddress(routed string, address string) (string, error) {
+	parsed, err := url.Parse(routed)
+	if err != nil {
+		return "", newWorkloadError(ErrInvalid, "Invalid uri '%s': %v", routed, err)
+	}
+	parsed.Scheme = strings.Replace(parsed.Scheme, "neo4j", "bolt", 1)
+	parsed.Host = address
+	parsed.Path = ""
+	parsed.RawQuery = ""
+	return parsed.String(), nil
+}
+
+// The members in the result of dbms.routing.getRoutingTable, which has a list of servers, each with addresses and a role
+func parseRoutingTable(result *Neo4jResult) ([]RoutingMember, error) {
+	servers := columnIndex(result.Header, "servers")
+	if servers < 0 || len(result.Rows) != 1 {
+		return nil, errors.New(fmt.Sprintf("Unexpected routing table with columns %v and %d rows", result.Header, len(result.Rows)))
+	}
+	entries, ok := result.Rows[0][servers].([]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Unexpected servers in routing table: %v", result.Rows[0][servers]))
+	}
+	byAddress := map[string]int{}
+	members := []RoutingMember{}
+	for _, entry := range entries {
+		server, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Unexpected server in routing table: %v", entry))
+		}
+		role, _ := server["role"].(string)
+		addresses, _ := server["addresses"].([]interface{})
+		for _, value := range addresses {
+			address, ok := value.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Unexpected address in routing table: %v", value))
+			}
+			if _, found := byAddress[address]; !found {
+				members = append(members, RoutingMember{Address: address, Roles: []string{}})
+				byAddress[address] = len(members) - 1
+			}
+		}
+		for _, value := range addresses {
+			member := &members[byAddress[value.(string)]]
+			if !member.hasRole(role) {
+				member.Roles = append(member.Roles, role)
+			}
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Address < members[j].Address
+	})
+	for i := range members {
+		sort.Strings(members[i].Roles)
+	}
+	return members, nil
+}
+
+// The members of the cluster serving the database of the job, read from the routing table through the routed uri
+func (w *Workload) ResolveMembers(client *Neo4jJob) ([]RoutingMember, error) {
+	if strings.Contains(client.dbid, memberSeparator) {
+		return nil, newWorkloadError(ErrInvalid, "Job '%s' is already a direct job for a member", client.dbid)
+	}
+	runner, err := w.runnerMaker.NewQuerySession(client.neo4j, neo4j.AccessModeRead)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close()
+	return client.routingMembers(runner)
+}
+
+func (n *Neo4jJob) routingMembers(runner QuerySession) ([]RoutingMember, error) {
+	// the database name is safe to quote, as it was checked against databaseNamePattern
+	result, err := runner.RunCypherQuery(neo4j.AccessModeRead, fmt.Sprintf("CALL dbms.routing.getRoutingTable({}, '%s')", n.neo4j.database))
+	if err != nil {
+		return nil, err
+	}
+	members, err := parseRoutingTable(result)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		members[i].Job = memberJobName(n.dbid, members[i].Address)
+	}
+	return members, nil
+}
+
+// A direct job for the member with the same database, credentials and workload as the job, without writes on members
+// that cannot take them
+func (n *Neo4jJob) memberJob(member RoutingMember) (*Neo4jJob, error) {
+	address, err := directAddress(n.neo4j.neo4jAddress, member.Address)
+	if err != nil {
+		return nil, err
+	}
+	direct := n.neo4j
+	direct.dbid = member.Job
+	direct.neo4jAddress = address
+	job := NewNeo4jJob(direct)
+	job.label = fmt.Sprintf("%s %s", n.dbid, strings.Join(member.Roles, ","))
+	job.workload, job.profile = n.workload, n.profile
+	if !member.hasRole("WRITE") {
+		job.profile.WriteRate = 0
+	}
+	// replication lag is measured by the job itself, with its own direct connections, and causal reads and contention
+	// need the leader
+	job.profile.LagRate, job.profile.CausalRate, job.profile.Contention = 0, 0, ContentionProfile{}
+	return job, nil
+}
+
+// Add a direct job for each member of the cluster of the job, keeping those that already exist
+func (w *Workload) AddMembers(client *Neo4jJob) ([]*Neo4jJob, error) {
+	members, err := w.ResolveMembers(client)
+	if err != nil {
+		return nil, err
+	}
+	jobs := []*Neo4jJob{}
+	for _, member := range members {
+		err, existing := w.Find(&Neo4jJob{dbid: member.Job, neo4j: Neo4j{dbid: member.Job}})
+		if err == nil {
+			jobs = append(jobs, existing)
+			continue
+		}
+		job, err := client.memberJob(member)
+		if err == nil {
+			err = w.Add(job)
+		}
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Remove the direct jobs for the members of the job, returning how many there were
+func (w *Workload) RemoveMembers(client *Neo4jJob) int {
+	removed := 0
+	for _, job := range w.List() {
+		if strings.HasPrefix(job.dbid, client.dbid+memberSeparator) {
+			if w.Remove(job) == nil {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+func (s *Server) apiMembersHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	switch request.Method {
+	case http.MethodGet:
+		members, err := workload.ResolveMembers(job)
+		if err != nil {
+			s.handleAPIError(writer, "Failed to resolve members", err)
+		} else {
+			s.writeJSON(writer, http.StatusOK, members)
+		}
+	case http.MethodPost:
+		jobs, err := workload.AddMembers(job)
+		if err != nil {
+			s.handleAPIError(writer, "Failed to add member jobs", err)
+		} else {
+			s.writeJSON(writer, http.StatusOK, makeJobStatuses(jobs, workload))
+		}
+	case http.MethodDelete:
+		workload.RemoveMembers(job)
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		s.methodNotAllowed(writer, request, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
@@ -0,0 +1,162 @@
+package benchmark
+
+import (
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func mockMemberWorkload(t *testing.T) *Workload {
+	workload := NewWorkload(&TestSessionMaker{})
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	leader := Member{"core-1:7687", "Neo4j/4.2.0"}
+	follower := Member{"core-2:7687", "Neo4j/4.2.0"}
+	for i := 0; i < 10; i++ {
+		workload.record(Message{verb: "read", dbid: "abc", value: int64(10 + i), server: follower})
+		workload.record(Message{verb: "write", dbid: "abc", value: int64(100 + i), server: leader})
+	}
+	workload.record(Message{verb: "read", dbid: "abc", value: 500, server: leader})
+	workload.record(Message{verb: "read", dbid: "abc", value: 5})
+	workload.record(Message{verb: "read:error", dbid: "abc", value: 1, message: "Connection refused"})
+	return workload
+}
+
+func Test_ResultsByMember(t *testing.T) {
+	workload := mockMemberWorkload(t)
+	assert.Equal(t, []Member{{"core-2:7687", "Neo4j/4.2.0"}, {"core-1:7687", "Neo4j/4.2.0"}, {}}, workload.results.members)
+	reads := workload.results.For("abc", "read")
+	assert.Equal(t, 12, len(reads.members))
+	assert.Equal(t, map[Member][]int64{
+		{"core-2:7687", "Neo4j/4.2.0"}: {10, 11, 12, 13, 14, 15, 16, 17, 18, 19},
+		{"core-1:7687", "Neo4j/4.2.0"}: {500},
+		{}:                             {5},
+	}, workload.results.ByMember(reads))
+
+	workload.results.Clear()
+	assert.Equal(t, 0, len(workload.results.members))
+	assert.Equal(t, map[Member][]int64{}, workload.results.ByMember(workload.results.For("abc", "read")))
+}
+
+func Test_MemberSummary(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := mockMemberWorkload(t)
+
+	tests := []struct {
+		path       string
+		statuscode int
+		expected   string
+	}{
+		{"/stats/abc/read/members", http.StatusOK, `{"Header":["server","agent","count","min","p50","p90","p99","max"],"Rows":[["","",1,5,5,5,5,5],["core-1:7687","Neo4j/4.2.0",1,500,500,500,500,500],["core-2:7687","Neo4j/4.2.0",10,10,14,18,19,19]]}`},
+		{"/stats/abc/write/members", http.StatusOK, `{"Header":["server","agent","count","min","p50","p90","p99","max"],"Rows":[["core-1:7687","Neo4j/4.2.0",10,100,104,108,109,109]]}`},
+		{"/stats/xyz/write/members", http.StatusOK, `{"Header":["server","agent","count","min","p50","p90","p99","max"],"Rows":[]}`},
+		{"/stats/abc/other/members", http.StatusBadRequest, `{"error":"Invalid result verb: other","message":"Failed to get results"}`},
+		{"/stats/abc/read/other", http.StatusBadRequest, `{"message":"invalid path for 'stats' request: /stats/abc/read/other"}`},
+		{apiPrefix + "/stats/abc/write/members", http.StatusOK, `{"Header":["server","agent","count","min","p50","p90","p99","max"],"Rows":[["core-1:7687","Neo4j/4.2.0",10,100,104,108,109,109]]}`},
+		{apiPrefix + "/stats/xyz/write/members", http.StatusNotFound, `{"error":{"status":404,"message":"Failed to get results","detail":"Could not find client for database 'xyz'"}}`},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		if strings.HasPrefix(test.path, apiPrefix) {
+			s.apiHandler(workload)(recorder, mockRequest(test.path, nil))
+		} else {
+			s.resultsHandler(workload)(recorder, mockRequest(test.path, nil))
+		}
+		assert.Equal(t, test.statuscode, recorder.Code, test.path)
+		assert.Equal(t, test.expected, recorder.Body.String(), test.path)
+	}
+}
+
+// Answers the routing table procedure with a leader, a follower and a read replica
+type TestRoutingSessionMaker struct {
+	TestSessionMaker
+}
+
+type TestRoutingSession struct {
+	TestQuerySession
+}
+
+func (m *TestRoutingSessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
+	return &TestRoutingSession{}, nil
+}
+
+func (r *TestRoutingSession) RunCypherQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	if !strings.HasPrefix(query, "CALL dbms.routing.getRoutingTable") {
+		return r.TestQuerySession.RunCypherQuery(accessMode, query)
+	}
+	result := NewNeo4jResult([]string{"ttl", "servers"})
+	result.add([]interface{}{int64(300), []interface{}{
+		map[string]interface{}{"addresses": []interface{}{"core-1:7687"}, "role": "WRITE"},
+		map[string]interface{}{"addresses": []interface{}{"core-2:7687", "replica-1:7687"}, "role": "READ"},
+		map[string]interface{}{"addresses": []interface{}{"core-1:7687", "core-2:7687"}, "role": "ROUTE"},
+	}})
+	return result, nil
+}
+
+func Test_DirectAddress(t *testing.T) {
+	tests := []struct {
+		routed   string
+		expected string
+	}{
+		{"neo4j+s://abc.databases.neo4j.io", "bolt+s://core-1:7687"},
+		{"neo4j+ssc://abc.databases.neo4j.io:7687", "bolt+ssc://core-1:7687"},
+		{"neo4j://localhost:7687", "bolt://core-1:7687"},
+		{"bolt://localhost:7687", "bolt://core-1:7687"},
+	}
+	for _, test := range tests {
+		address, err := directAddress(test.routed, "core-1:7687")
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, address, test.routed)
+	}
+}
+
+func Test_MemberJobs(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := NewWorkload(&TestRoutingSessionMaker{})
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	job.profile = WorkloadProfile{ReadRate: 2, WriteRate: 1}
+	assert.Nil(t, workload.Add(job))
+
+	tests := []struct {
+		method     string
+		path       string
+		statuscode int
+		expected   string
+	}{
+		{http.MethodGet, apiPrefix + "/jobs/abc/members", http.StatusOK, `[{"address":"core-1:7687","roles":["ROUTE","WRITE"],"job":"abc~core-1:7687"},` +
+			`{"address":"core-2:7687","roles":["READ","ROUTE"],"job":"abc~core-2:7687"},{"address":"replica-1:7687","roles":["READ"],"job":"abc~replica-1:7687"}]`},
+		{http.MethodPost, apiPrefix + "/jobs/abc/members", http.StatusOK, `[{"dbid":"abc~core-1:7687","label":"abc ROUTE,WRITE","address":"bolt+s://core-1:7687","database":"neo4j","running":false,"read":0,"write":0},` +
+			`{"dbid":"abc~core-2:7687","label":"abc READ,ROUTE","address":"bolt+s://core-2:7687","database":"neo4j","running":false,"read":0,"write":0},` +
+			`{"dbid":"abc~replica-1:7687","label":"abc READ","address":"bolt+s://replica-1:7687","database":"neo4j","running":false,"read":0,"write":0}]`},
+		{http.MethodPost, apiPrefix + "/jobs/abc/members", http.StatusOK, `[{"dbid":"abc~core-1:7687","label":"abc ROUTE,WRITE","address":"bolt+s://core-1:7687","database":"neo4j","running":false,"read":0,"write":0},` +
+			`{"dbid":"abc~core-2:7687","label":"abc READ,ROUTE","address":"bolt+s://core-2:7687","database":"neo4j","running":false,"read":0,"write":0},` +
+			`{"dbid":"abc~replica-1:7687","label":"abc READ","address":"bolt+s://replica-1:7687","database":"neo4j","running":false,"read":0,"write":0}]`},
+		{http.MethodGet, apiPrefix + "/jobs/abc~core-2:7687/members", http.StatusBadRequest, `{"error":{"status":400,"message":"Failed to resolve members","detail":"Job 'abc~core-2:7687' is already a direct job for a member"}}`},
+		{http.MethodPut, apiPrefix + "/jobs/abc/members", http.StatusMethodNotAllowed, `{"error":{"status":405,"message":"Method PUT not allowed for /api/v1/jobs/abc/members"}}`},
+		{http.MethodGet, apiPrefix + "/jobs/xyz/members", http.StatusNotFound, `{"error":{"status":404,"message":"Failed to find job","detail":"Could not find client for database 'xyz'"}}`},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		request := mockRequest(test.path, nil)
+		request.Method = test.method
+		s.apiHandler(workload)(recorder, request)
+		assert.Equal(t, test.statuscode, recorder.Code, test.method+" "+test.path)
+		assert.Equal(t, test.expected, recorder.Body.String(), test.method+" "+test.path)
+	}
+
+	assert.Equal(t, 4, len(workload.List()))
+	leader, follower := workload.List()[1], workload.List()[2]
+	assert.Equal(t, WorkloadProfile{ReadRate: 2, WriteRate: 1}, leader.profile)
+	assert.Equal(t, WorkloadProfile{ReadRate: 2}, follower.profile)
+	assert.Equal(t, "secret", follower.neo4j.password)
+
+	recorder := httptest.NewRecorder()
+	request := mockRequest(apiPrefix+"/jobs/abc/members", nil)
+	request.Method = http.MethodDelete
+	s.apiHandler(workload)(recorder, request)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, []*Neo4jJob{job}, workload.List())
+}
@@ -2,6 +2,7 @@ package benchmark
 
 import (
 	"errors"
+	"fmt"
 	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
 	"log"
 	"time"
@@ -10,6 +11,8 @@ import (
 type QuerySession interface {
 	Check() error
 	RunCypherQuery(accessMode neo4j.AccessMode, query string) (result *Neo4jResult, err error)
+	RunCausalQuery(accessMode neo4j.AccessMode, query string, bookmark string) (result *Neo4jResult, lastBookmark string, err error)
+	RunAutoCommitQuery(accessMode neo4j.AccessMode, query string) (result *Neo4jResult, err error)
 	Close() error
 }
 
@@ -19,16 +22,26 @@ type Neo4j struct {
 	neo4jAddress string
 	username     string
 	password     string
+	credentials  string // name of the credentials in the CredentialStore, used instead of username and password
 }
 
+// Each session has its own driver, which is closed with the session
 type Neo4jSession struct {
 	neo4j   Neo4j
+	driver  neo4j.Driver
 	session neo4j.Session
 }
 
-func NewNeo4j(dbid string, neo4jAddress string, username string, password string) *Neo4j {
-	log.Printf("Starting Neo4j Database Client for '%s'", dbid)
-	return &Neo4j{dbid, "neo4j", neo4jAddress, username, password}
+const defaultDatabase = "neo4j"
+
+func NewNeo4j(dbid string, neo4jAddress string, database string, username string, password string) *Neo4j {
+	log.Printf("Starting Neo4j Database Client for '%s' on database '%s'", dbid, database)
+	return &Neo4j{dbid, database, neo4jAddress, username, password, ""}
+}
+
+func NewNeo4jWithCredentials(dbid string, neo4jAddress string, database string, credentials string) *Neo4j {
+	log.Printf("Starting Neo4j Database Client for '%s' on database '%s' using credentials '%s'", dbid, database, credentials)
+	return &Neo4j{dbid, database, neo4jAddress, "", "", credentials}
 }
 
 func (s *Neo4jSession) Check() error {
@@ -46,8 +59,36 @@ func (s *Neo4jSession) RunCypherQuery(accessMode neo4j.AccessMode, query string)
 	return s.neo4j.runCypherQueryWithColumns(s.session, accessMode, query, []string{})
 }
 
+// Run the query in a new session of the same driver with the access mode, which decides whether the query is routed
+// to a reader or a writer
+func (s *Neo4jSession) RunAutoCommitQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	session := s.driver.NewSession(neo4j.SessionConfig{AccessMode: accessMode, DatabaseName: s.neo4j.database})
+	defer session.Close()
+	return s.neo4j.runAutoCommitQuery(session, query)
+}
+
+// Run the query in a new session of the same driver, which waits until the server has caught up with the bookmark if
+// there is one, and return the bookmark of the query
+func (s *Neo4jSession) RunCausalQuery(accessMode neo4j.AccessMode, query string, bookmark string) (*Neo4jResult, string, error) {
+	config := neo4j.SessionConfig{AccessMode: accessMode, DatabaseName: s.neo4j.database}
+	if bookmark != "" {
+		config.Bookmarks = []string{bookmark}
+	}
+	session := s.driver.NewSession(config)
+	defer session.Close()
+	result, err := s.neo4j.runCypherQueryWithColumns(session, accessMode, query, []string{})
+	if err != nil {
+		return nil, "", err
+	}
+	return result, session.LastBookmark(), nil
+}
+
 func (s *Neo4jSession) Close() error {
-	return s.session.Close()
+	err := s.session.Close()
+	if driverErr := s.driver.Close(); err == nil {
+		err = driverErr
+	}
+	return err
 }
 
 func (n *Neo4j) runCypherQueryWithColumns(session neo4j.Session, accessMode neo4j.AccessMode, query string, columns []string) (result *Neo4jResult, err error) {
@@ -61,22 +102,89 @@ func (n *Neo4j) runCypherQueryWithColumnsAndRows(session neo4j.Session, accessMo
 	if accessMode == neo4j.AccessModeWrite {
 		inTx = session.WriteTransaction
 	}
+	var server Member
+	var timing Timing
+	// the driver calls the function again for each retry, after an error returned by the function or by the commit
+	attempts := []Attempt{}
+	started := time.Now()
+	var previousStarted time.Time
 	records, err := inTx(func(tx neo4j.Transaction) (interface{}, error) {
-		var records []neo4j.Record
-		results, err := tx.Run(query, nil)
+		// the session has acquired a connection and begun the transaction by now
+		attemptStarted := time.Now()
+		if len(attempts) == 0 {
+			timing.Connection = attemptStarted.Sub(started).Milliseconds()
+		} else {
+			// the previous attempt failed, and lasted until this one started, including the wait before retrying and
+			// acquiring a connection again, so that the connection time is only that of the first attempt
+			previous := &attempts[len(attempts)-1]
+			previous.Duration = attemptStarted.Sub(previousStarted).Milliseconds()
+			if previous.Error == "" {
+				previous.Error = commitFailed
+			}
+		}
+		previousStarted = attemptStarted
+		records, summary, err := collectRecords(tx.Run(query, nil))
 		if err != nil {
 			log.Printf("Unable to run the query '%s' on database %s of deployment %s - %v", query, n.database, n.dbid, err)
+			attempts = append(attempts, Attempt{time.Since(attemptStarted).Milliseconds(), errorCode(err)})
 			return nil, err
 		}
-		var rec *neo4j.Record
-		for results.NextRecord(&rec) {
-			records = append(records, *rec)
-		}
+		attempts = append(attempts, Attempt{time.Since(attemptStarted).Milliseconds(), ""})
+		server = Member{summary.Server().Address(), summary.Server().Version()}
+		timing.Server = summary.ResultAvailableAfter().Milliseconds()
+		timing.Streaming = summary.ResultConsumedAfter().Milliseconds()
+		timing.measured = true
 		return records, nil
 	})
 	if err != nil {
+		if len(attempts) == 0 {
+			return nil, err
+		}
+		if attempts[len(attempts)-1].Error == "" {
+			attempts[len(attempts)-1].Error = commitFailed
+		}
+		return nil, &retriedError{err, attempts}
+	}
+	timing.Attempts = len(attempts)
+	for _, attempt := range attempts[:len(attempts)-1] {
+		timing.retrying += attempt.Duration
+	}
+	return makeFilteredResult(records, columns, rows, server, timing, attempts)
+}
+
+// Run the query in an auto-commit transaction, which the driver does not retry. The connection is acquired while the
+// query runs, so its duration is not broken down.
+func (n *Neo4j) runAutoCommitQuery(session neo4j.Session, query string) (*Neo4jResult, error) {
+	log.Printf("About to run the Cypher query '%s' in an auto-commit transaction on database %s of deployment %s", query, n.database, n.dbid)
+	started := time.Now()
+	records, summary, err := collectRecords(session.Run(query, nil))
+	if err != nil {
+		log.Printf("Unable to run the query '%s' on database %s of deployment %s - %v", query, n.database, n.dbid, err)
 		return nil, err
 	}
+	server := Member{summary.Server().Address(), summary.Server().Version()}
+	timing := Timing{Server: summary.ResultAvailableAfter().Milliseconds(), Streaming: summary.ResultConsumedAfter().Milliseconds(), Attempts: 1}
+	return makeFilteredResult(records, []string{}, map[string]interface{}{}, server, timing, []Attempt{{time.Since(started).Milliseconds(), ""}})
+}
+
+// All records of the result and its summary
+func collectRecords(results neo4j.Result, err error) ([]neo4j.Record, neo4j.ResultSummary, error) {
+	if err != nil {
+		return nil, nil, err
+	}
+	var records []neo4j.Record
+	var rec *neo4j.Record
+	for results.NextRecord(&rec) {
+		records = append(records, *rec)
+	}
+	summary, err := results.Consume()
+	if err != nil {
+		return nil, nil, err
+	}
+	return records, summary, nil
+}
+
+func makeFilteredResult(records interface{}, columns []string, rows map[string]interface{}, server Member, timing Timing, attempts []Attempt) (*Neo4jResult, error) {
 	neo4jResult, err := makeNeo4jResult(&records)
 	if err != nil {
 		return nil, err
@@ -87,10 +195,37 @@ func (n *Neo4j) runCypherQueryWithColumnsAndRows(session neo4j.Session, accessMo
 	if len(columns) > 0 {
 		neo4jResult = neo4jResult.FilterResultByColumns(columns)
 	}
+	neo4jResult.server = server
+	neo4jResult.timing = timing
+	neo4jResult.attempts = attempts
 	return neo4jResult, err
 }
 
+// The Neo4j status code of the error, like Neo.TransientError.Transaction.DeadlockDetected, or the error itself
+func errorCode(err error) string {
+	if neo4jError, ok := err.(*neo4j.Neo4jError); ok {
+		return neo4jError.Code
+	}
+	return err.Error()
+}
+
 type QuerySessionMaker struct {
+	credentials *CredentialStore
+}
+
+// Credentials are looked up each time a session is created, so that rotated passwords are used by new sessions
+func (m *QuerySessionMaker) authFor(n Neo4j) (neo4j.AuthToken, error) {
+	if n.credentials == "" {
+		return neo4j.BasicAuth(n.username, n.password, ""), nil
+	}
+	if m.credentials == nil {
+		return neo4j.AuthToken{}, errors.New(fmt.Sprintf("No credential store configured for credentials '%s'", n.credentials))
+	}
+	credentials, err := m.credentials.Get(n.credentials)
+	if err != nil {
+		return neo4j.AuthToken{}, err
+	}
+	return neo4j.BasicAuth(credentials.Username, credentials.Password, ""), nil
 }
 
 func (m *QuerySessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
@@ -98,14 +233,18 @@ func (m *QuerySessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode
 		conf.Log = neo4j.ConsoleLogger(neo4j.INFO)
 	}
 
-	driver, err := neo4j.NewDriver(n.neo4jAddress, neo4j.BasicAuth(n.username, n.password, ""), configForNeo4j4)
+	auth, err := m.authFor(n)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := neo4j.NewDriver(n.neo4jAddress, auth, configForNeo4j4)
 	if err != nil {
 		return nil, err
 	}
 
 	sessionConfig := neo4j.SessionConfig{AccessMode: accessMode, DatabaseName: n.database}
 	session := driver.NewSession(sessionConfig)
-	return &Neo4jSession{n, session}, nil
+	return &Neo4jSession{n, driver, session}, nil
 }
 
 type QueryTimestampMaker struct {
@@ -5,14 +5,40 @@ import (
 	"fmt"
 	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
 	"log"
+	"sync"
 	"time"
 )
 
 type Neo4jJob struct {
-	dbid    string
-	neo4j   Neo4j
-	running bool
-	done    chan struct{}
+	dbid     string
+	label    string // human readable name, for display only
+	workload string // name of the workload profile, empty for the default
+	profile  WorkloadProfile
+	neo4j    Neo4j
+	running  bool           // from Start until Stop, guarded by lock
+	done     chan struct{}  // closed by Stop to end the goroutines of the job
+	lock     sync.Mutex     // guards running and done, as the handlers start, stop and show jobs concurrently
+	workers  sync.WaitGroup // the goroutines of the job, which close their sessions when they finish
+}
+
+// The rates of the read and write queries of a job, in queries per second, where zero disables that kind of query,
+// and of the markers written to measure replication lag and the causally consistent reads after writes, which are
+// disabled by default, as are the concurrent writers of the contention workload
+type WorkloadProfile struct {
+	ReadRate   float64           `json:"readRate" yaml:"readRate"`
+	WriteRate  float64           `json:"writeRate" yaml:"writeRate"`
+	LagRate    float64           `json:"lagRate,omitempty" yaml:"lagRate,omitempty"`
+	CausalRate float64           `json:"causalRate,omitempty" yaml:"causalRate,omitempty"`
+	Contention ContentionProfile `json:"contention,omitempty" yaml:"contention,omitempty"`
+	AutoCommit bool              `json:"autoCommit,omitempty" yaml:"autoCommit,omitempty"` // run the queries without retries
+}
+
+const defaultWorkload = "default"
+
+var defaultProfile = WorkloadProfile{ReadRate: 1, WriteRate: 1}
+
+func intervalOf(rate float64) time.Duration {
+	return time.Duration(float64(time.Second) / rate)
 }
 
 type SessionMaker interface {
@@ -32,7 +58,61 @@ func nameOf(accessMode neo4j.AccessMode) string {
 }
 
 func NewNeo4jJob(neo4j Neo4j) *Neo4jJob {
-	return &Neo4jJob{neo4j.dbid, neo4j, false, make(chan struct{}, 1)}
+	return &Neo4jJob{dbid: neo4j.dbid, profile: defaultProfile, neo4j: neo4j, done: make(chan struct{})}
+}
+
+func (n *Neo4jJob) isRunning() bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.running
+}
+
+// Wait for the interval, returning false if the job was stopped in the meantime
+func (n *Neo4jJob) sleep(interval time.Duration) bool {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-n.done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Wait for the next tick, returning false if the job was stopped in the meantime. Queries are paced by a ticker rather
+// than a sleep between them, so that the time they take does not lower the rate of the workload, unless a query takes
+// longer than the interval, in which case the next one starts as soon as it finishes.
+func (n *Neo4jJob) tick(ticker *time.Ticker) bool {
+	// a tick is usually waiting after a slow query, and select would pick it half the time after the job was stopped
+	select {
+	case <-n.done:
+		return false
+	default:
+	}
+	select {
+	case <-n.done:
+		return false
+	case <-ticker.C:
+		return true
+	}
+}
+
+// Run the function in a goroutine of the job, which Stop ends and waits for like the others, unless the job is not
+// running
+func (n *Neo4jJob) spawn(f func()) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.running {
+		n.spawnLocked(f)
+	}
+}
+
+func (n *Neo4jJob) spawnLocked(f func()) {
+	n.workers.Add(1)
+	go func() {
+		defer n.workers.Done()
+		f()
+	}()
 }
 
 func (n *Neo4jJob) createModel(maker SessionMaker) error {
@@ -55,7 +135,7 @@ func (n *Neo4jJob) createModel(maker SessionMaker) error {
 	}
 }
 
-func (n *Neo4jJob) runWorkload(ch chan Message, maker SessionMaker, accessMode neo4j.AccessMode, query string, expected int) {
+func (n *Neo4jJob) runWorkload(ch chan Message, maker SessionMaker, accessMode neo4j.AccessMode, query string, expected int, interval time.Duration) {
 	countErrors := 0
 	maxErrors := 10
 	accessModeName := nameOf(accessMode)
@@ -63,41 +143,37 @@ func (n *Neo4jJob) runWorkload(ch chan Message, maker SessionMaker, accessMode n
 	runner, err := maker.NewQuerySession(n.neo4j, accessMode)
 	if err != nil {
 		log.Printf("Failed to create runner for %s workload against '%s': %v", accessModeName, n.dbid, err)
-		ch <- Message{errorMsg, n.dbid, -1}
+		ch <- Message{verb: errorMsg, dbid: n.dbid, value: -1, message: err.Error()}
 	} else {
 		defer runner.Close()
-		if !n.running {
-			log.Printf("Unexpected found running=false when starting %s workload against '%s' (errors=%d, running=%v)", accessModeName, n.dbid, countErrors, n.running)
-			n.running = true
-		} else {
-			log.Printf("Starting %s workload against '%s' (errors=%d, running=%v)", accessModeName, n.dbid, countErrors, n.running)
-		}
-		for n.running && countErrors < maxErrors {
-			select {
-			case <-n.done:
-				log.Printf("Received 'done' message - terminating %s workload against '%s'", accessModeName, n.dbid)
-				n.running = false
-			default:
-				time.Sleep(time.Second)
-				log.Printf("About to run %s query against '%s'", accessModeName, n.dbid)
-				started := time.Now()
-				result, err := runner.RunCypherQuery(accessMode, query)
-				if err != nil {
-					log.Printf(
-						"Error running %s query against '%s': %v", accessModeName, n.dbid, err)
-					countErrors += 1
-					ch <- Message{errorMsg, n.dbid, int64(countErrors)}
-				} else if len(result.Rows) != 1 {
-					log.Printf("Incorrect number of result rows running %s query against '%s': expected %d rows but got %d", accessModeName, n.dbid, expected, len(result.Rows))
-					countErrors += 1
-					ch <- Message{errorMsg, n.dbid, int64(countErrors)}
-				} else {
-					duration := time.Since(started)
-					ch <- Message{accessModeName, n.dbid, duration.Milliseconds()}
+		log.Printf("Starting %s workload against '%s'", accessModeName, n.dbid)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for countErrors < maxErrors && n.tick(ticker) {
+			log.Printf("About to run %s query against '%s'", accessModeName, n.dbid)
+			started := time.Now()
+			result, err := n.run(runner, accessMode, query)
+			if err != nil {
+				log.Printf(
+					"Error running %s query against '%s': %v", accessModeName, n.dbid, err)
+				countErrors += 1
+				n.sendFailedRetries(ch, accessModeName, err)
+				ch <- Message{verb: errorMsg, dbid: n.dbid, value: int64(countErrors), message: err.Error()}
+			} else if len(result.Rows) != 1 {
+				log.Printf("Incorrect number of result rows running %s query against '%s': expected %d rows but got %d", accessModeName, n.dbid, expected, len(result.Rows))
+				countErrors += 1
+				ch <- Message{verb: errorMsg, dbid: n.dbid, value: int64(countErrors), message: fmt.Sprintf("expected %d rows but got %d", expected, len(result.Rows)), server: result.server}
+			} else {
+				duration := time.Since(started)
+				n.sendRetries(ch, accessModeName, result)
+				ch <- Message{verb: accessModeName, dbid: n.dbid, value: duration.Milliseconds(), server: result.server, timing: result.timing.withTotal(duration)}
+				if counter, ok := result.Rows[0][0].(int64); ok && accessMode == neo4j.AccessModeWrite {
+					// the new value of the counter, to check that no acknowledged write was lost
+					ch <- Message{verb: "counter", dbid: n.dbid, value: counter, server: result.server}
 				}
 			}
 		}
-		log.Printf("Finishing %s workload against '%s' (errors=%d, running=%v)", accessModeName, n.dbid, countErrors, n.running)
+		log.Printf("Finishing %s workload against '%s' (errors=%d)", accessModeName, n.dbid, countErrors)
 	}
 }
 
@@ -115,21 +191,69 @@ func (n *Neo4jJob) Check(maker SessionMaker) error {
 }
 
 func (n *Neo4jJob) Start(ch chan Message, maker SessionMaker) {
-	if !n.running {
-		err := n.createModel(maker)
-		if err != nil {
-			log.Printf("Failed to setup model for '%s': %v", n.dbid, err)
-			ch <- Message{"model:error", n.dbid, -1}
-		} else {
-			n.running = true
-			go n.runWorkload(ch, maker, neo4j.AccessModeRead, "MATCH (n:ClientBenchmark) RETURN count(n)", 1)
-			go n.runWorkload(ch, maker, neo4j.AccessModeWrite, "MATCH (n:ClientBenchmark) WHERE exists(n.counter) SET n.counter = n.counter + 1 RETURN n.counter", 1)
+	if n.isRunning() {
+		return
+	}
+	// the goroutines of the previous run read done until they finish, so it is only replaced after that
+	n.workers.Wait()
+	var err error
+	if n.profile.WriteRate > 0 {
+		// jobs without writes may run against members that cannot take them, like direct jobs for followers
+		err = n.createModel(maker)
+	}
+	if err != nil {
+		log.Printf("Failed to setup model for '%s': %v", n.dbid, err)
+		ch <- Message{verb: "model:error", dbid: n.dbid, value: -1, message: err.Error()}
+		return
+	}
+	contention := n.profile.Contention.enabled()
+	if contention {
+		if err := n.createContentionModel(maker); err != nil {
+			log.Printf("Failed to setup contention model for '%s': %v", n.dbid, err)
+			ch <- Message{verb: "contention:error", dbid: n.dbid, value: -1, message: err.Error()}
+			contention = false
+		}
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.running {
+		return
+	}
+	n.running = true
+	n.done = make(chan struct{})
+	if n.profile.ReadRate > 0 {
+		n.spawnLocked(func() {
+			n.runWorkload(ch, maker, neo4j.AccessModeRead, "MATCH (n:ClientBenchmark) RETURN count(n)", 1, intervalOf(n.profile.ReadRate))
+		})
+	}
+	if n.profile.WriteRate > 0 {
+		n.spawnLocked(func() {
+			n.runWorkload(ch, maker, neo4j.AccessModeWrite, "MATCH (n:ClientBenchmark) WHERE exists(n.counter) SET n.counter = n.counter + 1 RETURN n.counter", 1, intervalOf(n.profile.WriteRate))
+		})
+	}
+	if n.profile.LagRate > 0 {
+		n.spawnLocked(func() { n.runLagWorkload(ch, maker, intervalOf(n.profile.LagRate)) })
+	}
+	if n.profile.CausalRate > 0 {
+		n.spawnLocked(func() { n.runCausalWorkload(ch, maker, intervalOf(n.profile.CausalRate)) })
+	}
+	if contention {
+		// the writers have their own sessions so that they run concurrently
+		for writer := 0; writer < n.profile.Contention.Writers; writer++ {
+			writer := writer
+			n.spawnLocked(func() { n.runContentionWriter(ch, maker, writer, intervalOf(n.profile.Contention.Rate)) })
 		}
 	}
 }
 
+// Tell all goroutines of the job to finish, without waiting for them, which is done with workers
 func (n *Neo4jJob) Stop() {
-	n.done <- struct{}{}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.running {
+		n.running = false
+		close(n.done)
+	}
 }
 
 func makeNeo4jResult(obj *interface{}) (*Neo4jResult, error) {
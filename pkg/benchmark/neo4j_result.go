@@ -52,12 +52,15 @@ import (
 //}
 
 type Neo4jResult struct {
-	Header []string
-	Rows   [][]interface{}
+	Header   []string
+	Rows     [][]interface{}
+	server   Member    // the cluster member that returned the result, from the result summary
+	timing   Timing    // how long the parts of the query took, if it was run by the driver
+	attempts []Attempt // the runs of the transaction function, of which only the last one succeeded
 }
 
 func NewNeo4jResult(keys []string) *Neo4jResult {
-	return &Neo4jResult{keys, [][]interface{}{}}
+	return &Neo4jResult{keys, [][]interface{}{}, Member{}, Timing{}, nil}
 }
 
 func (r *Neo4jResult) add(values []interface{}) {
@@ -0,0 +1,378 @@
+package benchmark
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Every route served by the benchmark is described once in apiOperations. This is used to generate both the OpenAPI 3
+// document served at /openapi.json and the help text of the index page, so the two can no longer drift apart.
+// The test in openapi_test.go checks that every pattern registered in Server.routes is described here.
+
+type apiParameter struct {
+	name        string
+	in          string
+	schemaType  string
+	description string
+}
+
+type apiOperation struct {
+	method      string
+	path        string
+	summary     string
+	parameters  []apiParameter
+	requestBody string
+	status      int
+	response    string
+	mediaType   string
+}
+
+var (
+	dbidParameter       = apiParameter{"dbid", "path", "string", "Database ID of the job"}
+	verbParameter       = apiParameter{"verb", "path", "string", "Type of query, either 'read' or 'write'"}
+	windowParameter     = apiParameter{"window", "query", "integer", "Size of the percentile window in seconds (default 10)"}
+	logParameter        = apiParameter{"log", "query", "boolean", "Use a logarithmic scale for the y-axis"}
+	percentileParameter = apiParameter{"percentile", "query", "number", "Percentile to chart (default 99)"}
+)
+
+var apiOperations = []apiOperation{
+	{"get", "/", "show commands", nil, "", http.StatusOK, "", contentTypeText},
+	{"get", "/openapi.json", "OpenAPI specification of all routes", nil, "", http.StatusOK, "", contentTypeJSON},
+	{"get", "/healthz", "liveness probe, fails if the workload is stuck", nil, "", http.StatusOK, "", contentTypeText},
+	{"get", "/readyz", "readiness probe, fails until the configuration is applied", nil, "", http.StatusOK, "", contentTypeText},
+	{"get", "/status", "show state of the workload and each job", nil, "", http.StatusOK, "ServiceStatus", contentTypeJSON},
+	{"get", "/neo4j/add/{dbid}", "add workload for database", []apiParameter{dbidParameter, {"uri", "query", "string", "Explicit address, instead of the address made from the dbid"}, {"database", "query", "string", "Name of the database to benchmark, defaults to 'neo4j'"}, {"credentials", "query", "string", "Name of the server-side Neo4j credentials to use"}}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/neo4j/remove/{dbid}", "remove workload for database", []apiParameter{dbidParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/neo4j/show/{dbid}", "show workload for database", []apiParameter{dbidParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/neo4j/list", "list current database workloads", nil, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/start", "start benchmark", nil, "", http.StatusOK, "StringResult", contentTypeJSON},
+	{"get", "/stop", "stop benchmark", nil, "", http.StatusOK, "StringResult", contentTypeJSON},
+	{"get", "/wait/{threshold}", "wait for a number of results", []apiParameter{{"threshold", "path", "integer", "Number of results to wait for"}}, "", http.StatusOK, "StringResult", contentTypeJSON},
+	{"get", "/stats", "get result counts", nil, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/stats/table", "get results of all databases as a table", nil, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/stats/{dbid}", "get read results for database", []apiParameter{dbidParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/stats/{dbid}/{verb}", "get results for database", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/stats/{dbid}/{verb}/members", "get results for database per cluster member", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/stats/{dbid}/{verb}/breakdown", "get results for database split into parts", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/stats/{dbid}/{verb}/attempts", "get attempts of each query for database", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", "/charts/table", "SVG chart of current results", []apiParameter{logParameter}, "", http.StatusOK, "", contentTypeSVG},
+	{"get", "/charts/percentiles", "SVG chart of percentiles", []apiParameter{percentileParameter, windowParameter, logParameter}, "", http.StatusOK, "", contentTypeSVG},
+	{"get", "/charts/{dbid}/{verb}", "SVG chart of percentiles for database", []apiParameter{dbidParameter, verbParameter, windowParameter, logParameter}, "", http.StatusOK, "", contentTypeSVG},
+	{"get", "/charts/{dbid}/{verb}/breakdown", "SVG chart of results split into parts", []apiParameter{dbidParameter, verbParameter, logParameter}, "", http.StatusOK, "", contentTypeSVG},
+	{"get", "/report", "download HTML or Markdown report", []apiParameter{{"format", "query", "string", "Either 'html' (default) or 'markdown'"}, windowParameter}, "", http.StatusOK, "", contentTypeHTML},
+	{"get", apiPrefix + "/jobs", "list jobs", nil, "", http.StatusOK, "JobStatusList", contentTypeJSON},
+	{"post", apiPrefix + "/jobs", "add job", nil, "JobSpec", http.StatusCreated, "JobStatus", contentTypeJSON},
+	{"get", apiPrefix + "/jobs/{dbid}", "show job", []apiParameter{dbidParameter}, "", http.StatusOK, "JobStatus", contentTypeJSON},
+	{"delete", apiPrefix + "/jobs/{dbid}", "remove job", []apiParameter{dbidParameter}, "", http.StatusNoContent, "", ""},
+	{"get", apiPrefix + "/jobs/{dbid}/topology", "show cluster topology and its changes for job", []apiParameter{dbidParameter}, "", http.StatusOK, "TopologyTimeline", contentTypeJSON},
+	{"get", apiPrefix + "/jobs/{dbid}/leader-switches", "show write metrics of each leader switch for job", []apiParameter{dbidParameter, windowParameter}, "", http.StatusOK, "LeaderSwitchMetricsList", contentTypeJSON},
+	{"get", apiPrefix + "/jobs/{dbid}/members", "list cluster members from the routing table of job", []apiParameter{dbidParameter}, "", http.StatusOK, "RoutingMemberList", contentTypeJSON},
+	{"post", apiPrefix + "/jobs/{dbid}/members", "add direct jobs for each cluster member of job", []apiParameter{dbidParameter}, "", http.StatusOK, "JobStatusList", contentTypeJSON},
+	{"delete", apiPrefix + "/jobs/{dbid}/members", "remove direct jobs for cluster members of job", []apiParameter{dbidParameter}, "", http.StatusNoContent, "", ""},
+	{"get", apiPrefix + "/jobs/{dbid}/lag", "get replication lag to each cluster member for job", []apiParameter{dbidParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/jobs/{dbid}/causal", "compare causally consistent reads after writes with plain reads for job", []apiParameter{dbidParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/jobs/{dbid}/integrity", "show acknowledged writes and integrity violations of the counter for job", []apiParameter{dbidParameter}, "", http.StatusOK, "IntegrityStatus", contentTypeJSON},
+	{"get", apiPrefix + "/jobs/{dbid}/contention", "get latency, retries and deadlocks of the concurrent writers of job", []apiParameter{dbidParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"post", apiPrefix + "/import", "add all databases in a table, JSON or YAML file", nil, "TableEntryList", http.StatusOK, "ImportResultList", contentTypeJSON},
+	{"post", apiPrefix + "/reload", "reload the configuration file and apply the changes", nil, "", http.StatusOK, "ConfigChanges", contentTypeJSON},
+	{"get", apiPrefix + "/discovery", "show databases discovered in Kubernetes", nil, "", http.StatusOK, "DiscoveryStatus", contentTypeJSON},
+	{"get", apiPrefix + "/credentials", "list names of server-side Neo4j credentials", nil, "", http.StatusOK, "CredentialNames", contentTypeJSON},
+	{"get", apiPrefix + "/workload", "show workload", nil, "", http.StatusOK, "WorkloadStatus", contentTypeJSON},
+	{"put", apiPrefix + "/workload", "start or stop workload", nil, "WorkloadSpec", http.StatusOK, "WorkloadStatus", contentTypeJSON},
+	{"get", apiPrefix + "/stats", "get result counts", nil, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/stats/table", "get results of all databases as a table", nil, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/stats/{dbid}/{verb}", "get results for database", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/stats/{dbid}/{verb}/members", "get results for database per cluster member", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/stats/{dbid}/{verb}/breakdown", "get results for database split into parts", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+	{"get", apiPrefix + "/stats/{dbid}/{verb}/attempts", "get attempts of each query for database", []apiParameter{dbidParameter, verbParameter}, "", http.StatusOK, "Neo4jResult", contentTypeJSON},
+}
+
+type object = map[string]interface{}
+
+func schemaRef(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func objectSchema(properties object, required ...string) object {
+	schema := object{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+var apiSchemas = object{
+	"Neo4jResult": objectSchema(object{
+		"Header": object{"type": "array", "items": object{"type": "string"}, "description": "Column names"},
+		"Rows":   object{"type": "array", "items": object{"type": "array", "items": object{}}, "description": "One array of values per row, in the same order as the header"},
+	}, "Header", "Rows"),
+	"StringResult": objectSchema(object{
+		"result": object{"type": "string"},
+	}, "result"),
+	"LegacyError": objectSchema(object{
+		"message": object{"type": "string"},
+		"error":   object{"type": "string"},
+	}, "message"),
+	"Error": objectSchema(object{
+		"error": objectSchema(object{
+			"status":  object{"type": "integer"},
+			"message": object{"type": "string"},
+			"detail":  object{"type": "string"},
+		}, "status", "message"),
+	}, "error"),
+	"JobSpec": objectSchema(object{
+		"dbid":        object{"type": "string", "description": "Aura database id, used to make the address if there is no uri"},
+		"label":       object{"type": "string", "description": "Human readable name, for display only"},
+		"alias":       object{"type": "string", "description": "Name of the job, defaults to the dbid or the host of the uri"},
+		"uri":         object{"type": "string", "description": "Explicit neo4j://, neo4j+s://, neo4j+ssc://, bolt://, bolt+s:// or bolt+ssc:// address"},
+		"database":    object{"type": "string", "description": "Name of the database to benchmark, defaults to 'neo4j'"},
+		"username":    object{"type": "string"},
+		"password":    object{"type": "string", "format": "password", "writeOnly": true},
+		"credentials": object{"type": "string", "description": "Name of server-side Neo4j credentials, instead of username and password"},
+		"workload":    object{"type": "string", "description": "Name of a workload from the configuration file, with the rates of the read and write queries"},
+	}),
+	"JobStatus": objectSchema(object{
+		"dbid":        object{"type": "string"},
+		"label":       object{"type": "string"},
+		"address":     object{"type": "string"},
+		"database":    object{"type": "string"},
+		"credentials": object{"type": "string"},
+		"workload":    object{"type": "string"},
+		"running":     object{"type": "boolean"},
+		"read":        object{"type": "integer"},
+		"write":       object{"type": "integer"},
+	}),
+	"JobStatusList":   object{"type": "array", "items": schemaRef("JobStatus")},
+	"CredentialNames": object{"type": "array", "items": object{"type": "string"}},
+	"TableEntry": objectSchema(object{
+		"name":        object{"type": "string", "description": "Human readable name, kept as the label of the job"},
+		"username":    object{"type": "string"},
+		"password":    object{"type": "string", "format": "password", "writeOnly": true},
+		"uri":         object{"type": "string"},
+		"database":    object{"type": "string"},
+		"credentials": object{"type": "string"},
+	}, "name", "uri"),
+	"TableEntryList": object{"type": "array", "items": schemaRef("TableEntry")},
+	"ImportResult": objectSchema(object{
+		"line":   object{"type": "integer", "description": "Line of the table, or index of the entry starting from 1"},
+		"label":  object{"type": "string"},
+		"dbid":   object{"type": "string"},
+		"status": object{"type": "integer", "description": "HTTP status of adding this entry, 201 if it was added"},
+		"error":  object{"type": "string"},
+	}, "line", "status"),
+	"ImportResultList": object{"type": "array", "items": schemaRef("ImportResult")},
+	"DiscoveryStatus": objectSchema(object{
+		"interval":         object{"type": "integer", "description": "Seconds between listings of the neo4jdatabases resources"},
+		"onlyChaosEnabled": object{"type": "boolean"},
+		"lastSync":         object{"type": "string", "format": "date-time"},
+		"discovered":       object{"type": "array", "items": object{"type": "string"}},
+		"errors":           object{"type": "array", "items": object{"type": "string"}, "description": "Errors of the last listing"},
+	}, "interval", "onlyChaosEnabled", "discovered", "errors"),
+	"TopologyChange": objectSchema(object{
+		"timestamp": object{"type": "integer"},
+		"database":  object{"type": "string"},
+		"address":   object{"type": "string"},
+		"role":      object{"type": "string", "description": "Empty if the member is no longer listed"},
+		"status":    object{"type": "string", "description": "Current status, or 'absent' if the member is no longer listed"},
+		"error":     object{"type": "string"},
+	}, "timestamp", "database", "address", "role", "status"),
+	"TopologyTimeline": objectSchema(object{
+		"dbid":      object{"type": "string"},
+		"lastPoll":  object{"type": "integer", "description": "Timestamp of the last poll, zero if there was none"},
+		"lastError": object{"type": "string"},
+		"members":   object{"type": "array", "items": schemaRef("TopologyChange")},
+		"changes":   object{"type": "array", "items": schemaRef("TopologyChange")},
+		"switches":  object{"type": "array", "items": schemaRef("LeaderSwitch")},
+	}, "dbid", "lastPoll", "members", "changes", "switches"),
+	"LeaderSwitch": objectSchema(object{
+		"timestamp": object{"type": "integer", "description": "When the previous leader was first seen not to be the leader"},
+		"detected":  object{"type": "integer", "description": "When the new leader was first seen"},
+		"database":  object{"type": "string"},
+		"from":      object{"type": "string"},
+		"to":        object{"type": "string"},
+	}, "timestamp", "detected", "database", "from", "to"),
+	"LeaderSwitchMetrics": objectSchema(object{
+		"timestamp":        object{"type": "integer"},
+		"detected":         object{"type": "integer"},
+		"database":         object{"type": "string"},
+		"from":             object{"type": "string"},
+		"to":               object{"type": "string"},
+		"unavailable":      object{"type": "integer", "description": "Seconds from the last successful write before the switch to the first after it, -1 if there was none after it yet"},
+		"timeToFirstWrite": object{"type": "integer", "description": "Seconds from the switch to the first successful write, -1 if there was none yet"},
+		"failedWrites":     object{"type": "integer"},
+		"baselineP99":      object{"type": "integer", "description": "p99 write latency in ms in the window before the switch"},
+		"afterP99":         object{"type": "integer", "description": "p99 write latency in ms in the window after the first successful write"},
+		"elevation":        object{"type": "number", "description": "Ratio of afterP99 to baselineP99, zero if either is unknown"},
+	}, "timestamp", "detected", "database", "from", "to", "unavailable", "timeToFirstWrite", "failedWrites", "baselineP99", "afterP99", "elevation"),
+	"LeaderSwitchMetricsList": object{"type": "array", "items": schemaRef("LeaderSwitchMetrics")},
+	"IntegrityViolation": objectSchema(object{
+		"timestamp": object{"type": "integer"},
+		"kind":      object{"type": "string", "enum": []string{"lost-update", "duplicate", "regression"}},
+		"value":     object{"type": "integer", "description": "Counter value returned by the write"},
+		"expected":  object{"type": "integer", "description": "Lowest value the counter should have had"},
+		"message":   object{"type": "string"},
+	}, "timestamp", "kind", "value", "expected", "message"),
+	"IntegrityStatus": objectSchema(object{
+		"dbid":         object{"type": "string"},
+		"acknowledged": object{"type": "integer", "description": "Number of successful writes"},
+		"first":        object{"type": "integer", "description": "First counter value returned, zero if there was none"},
+		"last":         object{"type": "integer"},
+		"lost":         object{"type": "integer", "description": "Acknowledged writes missing from the counter"},
+		"violations":   object{"type": "array", "items": schemaRef("IntegrityViolation")},
+	}, "dbid", "acknowledged", "first", "last", "lost", "violations"),
+	"RoutingMember": objectSchema(object{
+		"address": object{"type": "string"},
+		"roles":   object{"type": "array", "items": object{"type": "string", "enum": []string{"READ", "ROUTE", "WRITE"}}},
+		"job":     object{"type": "string", "description": "Name of the direct job for the member, '<dbid>~<address>'"},
+	}, "address", "roles", "job"),
+	"RoutingMemberList": object{"type": "array", "items": schemaRef("RoutingMember")},
+	"JobState": objectSchema(object{
+		"dbid":      object{"type": "string"},
+		"running":   object{"type": "boolean"},
+		"read":      object{"type": "integer"},
+		"write":     object{"type": "integer"},
+		"lastRead":  object{"type": "integer", "description": "Timestamp of the last read result, zero if there is none"},
+		"lastWrite": object{"type": "integer", "description": "Timestamp of the last write result, zero if there is none"},
+	}, "dbid", "running", "read", "write", "lastRead", "lastWrite"),
+	"ServiceStatus": objectSchema(object{
+		"running":         object{"type": "boolean"},
+		"ready":           object{"type": "boolean"},
+		"heartbeatAgeMs":  object{"type": "integer", "description": "Time since the read loop last ran, zero if not running"},
+		"backlog":         object{"type": "integer", "description": "Messages from jobs waiting to be recorded"},
+		"backlogCapacity": object{"type": "integer"},
+		"jobs":            object{"type": "array", "items": schemaRef("JobState")},
+	}, "running", "ready", "heartbeatAgeMs", "backlog", "backlogCapacity", "jobs"),
+	"ConfigChanges": objectSchema(object{
+		"added":     object{"type": "array", "items": object{"type": "string"}},
+		"removed":   object{"type": "array", "items": object{"type": "string"}},
+		"updated":   object{"type": "array", "items": object{"type": "string"}},
+		"unchanged": object{"type": "array", "items": object{"type": "string"}},
+		"errors":    object{"type": "array", "items": object{"type": "string"}},
+	}, "added", "removed", "updated", "unchanged", "errors"),
+	"WorkloadSpec": objectSchema(object{
+		"running": object{"type": "boolean"},
+	}, "running"),
+	"WorkloadStatus": objectSchema(object{
+		"running": object{"type": "boolean"},
+		"jobs":    schemaRef("JobStatusList"),
+	}),
+}
+
+func (o apiOperation) requiredRole() Role {
+	return requiredRole(&http.Request{Method: strings.ToUpper(o.method), URL: &url.URL{Path: o.path}})
+}
+
+func (o apiOperation) toOpenAPI() object {
+	parameters := []object{}
+	for _, p := range o.parameters {
+		parameters = append(parameters, object{
+			"name":        p.name,
+			"in":          p.in,
+			"required":    p.in == "path",
+			"description": p.description,
+			"schema":      object{"type": p.schemaType},
+		})
+	}
+	success := object{"description": o.summary}
+	if o.mediaType != "" {
+		schema := object{"type": "string"}
+		if o.mediaType == contentTypeJSON {
+			schema = object{"type": "object"}
+		}
+		if o.response != "" {
+			schema = schemaRef(o.response)
+		}
+		success["content"] = object{o.mediaType: object{"schema": schema}}
+	}
+	errorSchema := "LegacyError"
+	if strings.HasPrefix(o.path, apiPrefix) {
+		errorSchema = "Error"
+	}
+	operation := object{
+		"summary":    o.summary,
+		"parameters": parameters,
+		"responses": object{
+			fmt.Sprintf("%d", o.status): success,
+			"default": object{
+				"description": "Error",
+				"content":     object{contentTypeJSON: object{"schema": schemaRef(errorSchema)}},
+			},
+		},
+	}
+	role := o.requiredRole()
+	if role == RoleAnonymous {
+		operation["security"] = []object{}
+	} else {
+		operation["security"] = []object{{"basicAuth": []string{}}, {"bearerAuth": []string{}}, {"apiToken": []string{}}}
+		operation["x-required-role"] = role.String()
+	}
+	if o.requestBody != "" {
+		content := object{contentTypeJSON: object{"schema": schemaRef(o.requestBody)}}
+		if o.requestBody == "TableEntryList" {
+			// The import also accepts YAML, and the whitespace separated table format of latency-benchmark.txt
+			content[contentTypeYAML] = object{"schema": schemaRef(o.requestBody)}
+			content[contentTypeText] = object{"schema": object{"type": "string"}}
+		}
+		operation["requestBody"] = object{
+			"required": true,
+			"content":  content,
+		}
+	}
+	return operation
+}
+
+func openAPISpec() object {
+	paths := object{}
+	for _, o := range apiOperations {
+		item, ok := paths[o.path].(object)
+		if !ok {
+			item = object{}
+			paths[o.path] = item
+		}
+		item[o.method] = o.toOpenAPI()
+	}
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "Latency Benchmark",
+			"description": "Measures concurrent read and write latencies of Cypher queries against Neo4j databases",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": apiSchemas,
+			"securitySchemes": object{
+				"basicAuth":  object{"type": "http", "scheme": "basic"},
+				"bearerAuth": object{"type": "http", "scheme": "bearer"},
+				"apiToken":   object{"type": "apiKey", "in": "header", "name": apiTokenHeader},
+			},
+		},
+	}
+}
+
+// The documented paths, with path parameters in the help text format of '<DBID>'
+func helpCommands() [][]string {
+	commands := [][]string{}
+	seen := map[string]bool{}
+	for _, o := range apiOperations {
+		if seen[o.path] || strings.HasPrefix(o.path, apiPrefix) {
+			continue
+		}
+		seen[o.path] = true
+		path := o.path
+		for _, p := range o.parameters {
+			path = strings.ReplaceAll(path, "{"+p.name+"}", "<"+strings.ToUpper(p.name)+">")
+		}
+		commands = append(commands, []string{path, o.summary})
+	}
+	commands = append(commands, []string{apiPrefix + "/", "versioned REST API, see /openapi.json"})
+	return commands
+}
+
+func (s *Server) openAPIHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		s.writeJSON(writer, http.StatusOK, openAPISpec())
+	}
+}
@@ -0,0 +1,100 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Sample values for the parameters of documented paths
+var pathSamples = strings.NewReplacer("{dbid}", "abc", "{verb}", "read", "{threshold}", "0")
+
+// Every documented path, with sample values for its parameters, must reach a handler that accepts the path and method
+func Test_OpenAPIPathsAreRouted(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := NewWorkload(&TestRoutingSessionMaker{})
+	// a job without queries, so that starting the workload does not wait for them
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	job.profile = WorkloadProfile{}
+	mux := http.NewServeMux()
+	for _, route := range s.routes(workload) {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
+
+	for index, o := range apiOperations {
+		path := pathSamples.Replace(o.path)
+		t.Run(fmt.Sprintf("test_%d %s %s", index+1, o.method, path), func(t *testing.T) {
+			if err, _ := workload.Find(job); err != nil {
+				assert.Nil(t, workload.Add(job))
+			}
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, httptest.NewRequest(strings.ToUpper(o.method), path, nil))
+			body := recorder.Body.String()
+			assert.NotEqual(t, http.StatusMethodNotAllowed, recorder.Code, body)
+			for _, rejected := range []string{"Invalid request", "invalid path", "Invalid path"} {
+				assert.NotContains(t, body, rejected)
+			}
+		})
+	}
+	workload.Stop()
+}
+
+func Test_OpenAPIDocumentsAllRoutes(t *testing.T) {
+	s, workload := mockServer(t)
+	mux := http.NewServeMux()
+	routes := s.routes(workload)
+	for _, route := range routes {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
+	// the routes that the documented paths reach, rather than any route a documented path starts with
+	reached := map[string]bool{}
+	for path := range openAPISpec()["paths"].(object) {
+		_, pattern := mux.Handler(httptest.NewRequest("GET", pathSamples.Replace(path), nil))
+		reached[pattern] = true
+	}
+
+	for index, route := range routes {
+		t.Run(fmt.Sprintf("test_%d %s", index+1, route.pattern), func(t *testing.T) {
+			assert.True(t, reached[route.pattern], "Route '%s' is not documented in the OpenAPI specification", route.pattern)
+		})
+	}
+}
+
+func Test_OpenAPISchemas(t *testing.T) {
+	s, _ := mockServer(t)
+	request := httptest.NewRequest("GET", "/openapi.json", nil)
+	responseRecorder := httptest.NewRecorder()
+	s.openAPIHandler()(responseRecorder, request)
+	response := responseRecorder.Result()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, contentTypeJSON, response.Header.Get(contentType))
+
+	body, _ := ioutil.ReadAll(response.Body)
+	var spec map[string]interface{}
+	err := json.Unmarshal(body, &spec)
+	assert.Nil(t, err)
+	assert.Equal(t, "3.0.3", spec["openapi"])
+
+	// Every schema referenced by an operation must be defined in the components
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, o := range apiOperations {
+		for _, name := range []string{o.response, o.requestBody} {
+			if name != "" {
+				assert.Contains(t, schemas, name, "Schema for %s %s", o.method, o.path)
+			}
+		}
+		for _, p := range o.parameters {
+			if p.in == "path" {
+				assert.Contains(t, o.path, "{"+p.name+"}", "Path parameter for %s %s", o.method, o.path)
+			}
+		}
+	}
+	result := schemas["Neo4jResult"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Contains(t, result, "Header")
+	assert.Contains(t, result, "Rows")
+}
@@ -0,0 +1,87 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The publisher periodically patches the latest results of each discovered database onto its neo4jdatabases resource
+// as annotations, so that they can be seen with kubectl and used by other tooling. It is enabled by setting
+// PUBLISH_INTERVAL to the number of seconds between updates, and requires discovery to know which resource belongs to
+// which job. The latencies and error rate are of the reads and writes in the last PUBLISH_WINDOW seconds (default 60).
+// Stopped jobs are not published, so that the annotations keep showing when their results were last updated.
+
+const annotationPrefix = "latency-benchmark.neo4j.io/"
+
+type AnnotationPatcher interface {
+	PatchAnnotations(namespace string, name string, annotations map[string]string) error
+}
+
+type Publisher struct {
+	patcher  AnnotationPatcher
+	interval time.Duration
+	window   int64
+}
+
+func NewPublisher(patcher AnnotationPatcher, interval time.Duration, window int64) *Publisher {
+	return &Publisher{patcher, interval, window}
+}
+
+func formatTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+func (s LatencySnapshot) annotations(updated time.Time) map[string]string {
+	return map[string]string{
+		annotationPrefix + "read-p50-ms":  strconv.FormatInt(s.ReadP50, 10),
+		annotationPrefix + "read-p99-ms":  strconv.FormatInt(s.ReadP99, 10),
+		annotationPrefix + "write-p50-ms": strconv.FormatInt(s.WriteP50, 10),
+		annotationPrefix + "write-p99-ms": strconv.FormatInt(s.WriteP99, 10),
+		annotationPrefix + "error-rate":   strconv.FormatFloat(s.ErrorRate, 'f', 4, 64),
+		annotationPrefix + "last-success": formatTimestamp(s.LastSuccess),
+		annotationPrefix + "updated":      updated.UTC().Format(time.RFC3339),
+	}
+}
+
+// Patch the annotations of every target database that has a running job, returning the errors of any that failed
+func (p *Publisher) Publish(targets map[string]string, workload *Workload) []error {
+	failures := []error{}
+	dbids := []string{}
+	for dbid := range targets {
+		dbids = append(dbids, dbid)
+	}
+	sort.Strings(dbids)
+	now := time.Now()
+	for _, dbid := range dbids {
+		if err, job := workload.Find(&Neo4jJob{dbid: dbid, neo4j: Neo4j{dbid: dbid}}); err != nil || !job.isRunning() {
+			continue
+		}
+		annotations := workload.Snapshot(dbid, p.window).annotations(now)
+		if err := p.patcher.PatchAnnotations(targets[dbid], dbid, annotations); err != nil {
+			log.Printf("Failed to publish results of '%s': %v", dbid, err)
+			failures = append(failures, errors.New(fmt.Sprintf("%s: %v", dbid, err)))
+		}
+	}
+	return failures
+}
+
+// Publish at the interval until the done channel is closed
+func (p *Publisher) Run(discovery *Discovery, workload *Workload, done chan struct{}) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			p.Publish(discovery.Targets(), workload)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type TestAnnotationPatcher struct {
+	patched map[string]map[string]string
+	fail    string
+}
+
+func (p *TestAnnotationPatcher) PatchAnnotations(namespace string, name string, annotations map[string]string) error {
+	if name == p.fail {
+		return errors.New("forbidden")
+	}
+	p.patched[namespace+"/"+name] = annotations
+	return nil
+}
+
+func mockPublishedWorkload(t *testing.T) *Workload {
+	workload := NewWorkload(&TestSessionMaker{})
+	for _, dbid := range []string{"abc", "xyz"} {
+		err := workload.Add(NewNeo4jJob(*NewNeo4j(dbid, "neo4j+s://"+dbid+"-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+		assert.Nil(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		workload.results.Add("read", "abc", int64(10+i))
+		workload.results.Add("write", "abc", int64(100+i))
+	}
+	workload.addEvent("abc", "write:error", "No leader")
+	workload.addEvent("abc", "write:error", "No leader")
+	return workload
+}
+
+func Test_Snapshot(t *testing.T) {
+	workload := mockPublishedWorkload(t)
+	assert.Equal(t, LatencySnapshot{18, 19, 108, 109, 0, 20}, workload.Snapshot("abc", 6), "The write errors are older than the window")
+	// a read error within the window, and an error of another kind of query
+	workload.eventTimes = &TestTimestampMaker{counter: 17}
+	workload.addEvent("abc", "read:error", "Timeout")
+	workload.addEvent("abc", "lag:error", "Timeout")
+	assert.Equal(t, LatencySnapshot{18, 19, 108, 109, 1.0 / 7, 20}, workload.Snapshot("abc", 6))
+	assert.Equal(t, LatencySnapshot{14, 19, 104, 109, 3.0 / 23, 20}, workload.Snapshot("abc", 60))
+	assert.Equal(t, LatencySnapshot{}, workload.Snapshot("xyz", 60))
+}
+
+func Test_Publish(t *testing.T) {
+	workload := mockPublishedWorkload(t)
+	patcher := &TestAnnotationPatcher{patched: map[string]map[string]string{}}
+	publisher := NewPublisher(patcher, time.Minute, 6)
+	for _, job := range workload.List() {
+		job.running = true
+	}
+
+	targets := map[string]string{"abc": "neo4j-abc", "xyz": "neo4j-xyz", "gone": "neo4j-gone"}
+	failures := publisher.Publish(targets, workload)
+	assert.Equal(t, 0, len(failures))
+	assert.Equal(t, 2, len(patcher.patched), "Databases without a job should not be patched")
+	annotations := patcher.patched["neo4j-abc/abc"]
+	assert.Equal(t, "18", annotations["latency-benchmark.neo4j.io/read-p50-ms"])
+	assert.Equal(t, "19", annotations["latency-benchmark.neo4j.io/read-p99-ms"])
+	assert.Equal(t, "108", annotations["latency-benchmark.neo4j.io/write-p50-ms"])
+	assert.Equal(t, "109", annotations["latency-benchmark.neo4j.io/write-p99-ms"])
+	assert.Equal(t, "0.0000", annotations["latency-benchmark.neo4j.io/error-rate"])
+	assert.Equal(t, "1970-01-01T00:00:20Z", annotations["latency-benchmark.neo4j.io/last-success"])
+	assert.Equal(t, "", patcher.patched["neo4j-xyz/xyz"]["latency-benchmark.neo4j.io/last-success"])
+
+	patcher.fail = "xyz"
+	failures = publisher.Publish(targets, workload)
+	assert.Equal(t, 1, len(failures))
+	assert.Equal(t, "xyz: forbidden", failures[0].Error())
+
+	patcher.fail = ""
+	patcher.patched = map[string]map[string]string{}
+	for _, job := range workload.List() {
+		if job.dbid == "abc" {
+			job.Stop()
+		}
+	}
+	assert.Equal(t, 0, len(publisher.Publish(targets, workload)))
+	assert.NotContains(t, patcher.patched, "neo4j-abc/abc", "Stopped jobs should not be published")
+	assert.Contains(t, patcher.patched, "neo4j-xyz/xyz")
+}
+
+func Test_PublisherRunStops(t *testing.T) {
+	publisher := NewPublisher(&TestAnnotationPatcher{patched: map[string]map[string]string{}}, time.Hour, 60)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		publisher.Run(NewDiscovery(&TestDatabaseLister{}, time.Hour, false), NewWorkload(&TestSessionMaker{}), done)
+		close(finished)
+	}()
+	close(done)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Publisher did not stop")
+	}
+}
@@ -0,0 +1,264 @@
+package benchmark
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// A report is a self-contained summary of a benchmark run, intended to replace the hand-written summaries we used to
+// produce after each chaos drill. It can be rendered as Markdown (with charts embedded as data URIs) or as a single
+// HTML page (with inline SVG), so the result can be attached to a ticket without any other files.
+
+type Report struct {
+	Title            string
+	Generated        time.Time
+	Environment      string
+	Window           int64         // seconds per window of the percentile charts
+	TopologyInterval time.Duration // zero if the topology was not polled
+	Jobs             *Neo4jResult
+	Configuration    *Neo4jResult
+	Latencies        *Neo4jResult
+	Breakdown        *Neo4jResult
+	Errors           *Neo4jResult
+	Switches         *Neo4jResult
+	Lag              *Neo4jResult
+	Causal           *Neo4jResult
+	Integrity        *Neo4jResult
+	Contention       *Neo4jResult
+	Retries          *Neo4jResult
+	Events           []Event
+	Charts           []*Chart
+}
+
+func NewReport(environment string, workload *Workload, window int64) (*Report, error) {
+	jobs, err := makeNeo4jClientResult(workload.List(), workload)
+	if err != nil {
+		return nil, err
+	}
+	latencies, err := workload.LatencySummary()
+	if err != nil {
+		return nil, err
+	}
+	breakdown, err := workload.BreakdownSummary()
+	if err != nil {
+		return nil, err
+	}
+	errors, err := workload.ErrorSummary()
+	if err != nil {
+		return nil, err
+	}
+	events := workload.Events()
+	table, err := workload.ResultsTable()
+	if err != nil {
+		return nil, err
+	}
+	charts := []*Chart{}
+	if len(table.Rows) > 0 {
+		chart, err := NewChartFromResult("Latency", "duration (ms)", true, table)
+		if err != nil {
+			return nil, err
+		}
+		annotateChart(chart, events)
+		charts = append(charts, chart)
+		for _, p := range []float64{50, 99} {
+			percentiles, err := workload.PercentilesTable(p, window)
+			if err != nil {
+				return nil, err
+			}
+			chart, err := NewChartFromResult(fmt.Sprintf("p%v latency per %ds window", p, window), "duration (ms)", true, percentiles)
+			if err != nil {
+				return nil, err
+			}
+			annotateChart(chart, events)
+			charts = append(charts, chart)
+		}
+	}
+	title := "Latency benchmark report"
+	if environment != "" {
+		title = fmt.Sprintf("Latency benchmark report for %s", environment)
+	}
+	return &Report{
+		Title:            title,
+		Generated:        time.Now().UTC(),
+		Environment:      environment,
+		Window:           window,
+		TopologyInterval: workload.topologyInterval,
+		Jobs:             jobs,
+		Configuration:    configurationResult(workload.List()),
+		Latencies:        latencies,
+		Breakdown:        breakdown,
+		Errors:           errors,
+		Switches:         workload.LeaderSwitchSummary(window),
+		Lag:              workload.LagSummary(""),
+		Causal:           workload.CausalSummary(""),
+		Integrity:        workload.IntegritySummary(),
+		Contention:       workload.ContentionSummary(""),
+		Retries:          workload.RetrySummary(),
+		Events:           events,
+		Charts:           charts,
+	}, nil
+}
+
+// The workload profile of each job, in queries per second
+func configurationResult(clients []*Neo4jJob) *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "workload", "read rate", "write rate", "lag rate", "causal rate", "contention", "auto-commit"})
+	for _, client := range clients {
+		name := client.workload
+		if name == "" {
+			name = defaultWorkload
+		}
+		profile := client.profile
+		contention := ""
+		if profile.Contention.enabled() {
+			contention = fmt.Sprintf("%d %s writers at %v per second on %d nodes", profile.Contention.Writers, profile.Contention.Mode, profile.Contention.Rate, profile.Contention.nodes())
+		}
+		result.add([]interface{}{client.dbid, name, profile.ReadRate, profile.WriteRate, profile.LagRate, profile.CausalRate, contention, profile.AutoCommit})
+	}
+	return result
+}
+
+func (r *Report) topologyInterval() string {
+	if r.TopologyInterval == 0 {
+		return "off"
+	}
+	return r.TopologyInterval.String()
+}
+
+// The kinds of events that are marked on the charts of the report. Errors and topology changes can happen on every
+// query or poll, so they are only listed, as their labels would cover the charts.
+var annotatedEvents = map[string]bool{"add": true, "remove": true, "reconfigure": true, "restart": true, "start": true, "stop": true, "shutdown": true, "leader-switch": true}
+
+func annotateChart(chart *Chart, events []Event) {
+	for _, event := range events {
+		if annotatedEvents[event.kind] {
+			chart.Annotate(event.timestamp, event.label())
+		}
+	}
+}
+
+func (e Event) label() string {
+	if e.dbid == "" {
+		return e.kind
+	}
+	return fmt.Sprintf("%s %s", e.kind, e.dbid)
+}
+
+func (r *Report) eventsResult() *Neo4jResult {
+	result := NewNeo4jResult([]string{"timestamp", "time", "dbid", "kind", "message"})
+	for _, event := range r.Events {
+		when := time.Unix(event.timestamp, 0).UTC().Format(time.RFC3339)
+		result.add([]interface{}{event.timestamp, when, event.dbid, event.kind, event.message})
+	}
+	return result
+}
+
+func (r *Report) sections() []struct {
+	title  string
+	result *Neo4jResult
+} {
+	return []struct {
+		title  string
+		result *Neo4jResult
+	}{
+		{"Databases", r.Jobs},
+		{"Configuration", r.Configuration},
+		{"Latency percentiles (ms)", r.Latencies},
+		{"Latency breakdown (ms)", r.Breakdown},
+		{"Errors", r.Errors},
+		{"Leader switches (seconds, and ms for the p99 latencies)", r.Switches},
+		{"Replication lag (ms)", r.Lag},
+		{"Causally consistent reads (ms)", r.Causal},
+		{"Counter integrity", r.Integrity},
+		{"Write contention (ms)", r.Contention},
+		{"Retries in transaction functions (ms)", r.Retries},
+		{"Events", r.eventsResult()},
+	}
+}
+
+// Escape the characters that would end a table cell or start a link, in table cells and image descriptions
+var markdownEscaper = strings.NewReplacer("|", "\\|", "[", "\\[", "]", "\\]", "\n", " ")
+
+func markdownCell(value interface{}) string {
+	return markdownEscaper.Replace(toString(value))
+}
+
+func (r *Report) WriteMarkdown(writer io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title)
+	fmt.Fprintf(&b, "- Environment: `%s`\n", r.Environment)
+	fmt.Fprintf(&b, "- Generated: %s\n", r.Generated.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Percentile window: %ds\n", r.Window)
+	fmt.Fprintf(&b, "- Topology interval: %s\n", r.topologyInterval())
+	for _, section := range r.sections() {
+		fmt.Fprintf(&b, "\n## %s\n\n", section.title)
+		if len(section.result.Rows) == 0 {
+			b.WriteString("None\n")
+			continue
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(section.result.Header, " | "))
+		fmt.Fprintf(&b, "|%s\n", strings.Repeat(" --- |", len(section.result.Header)))
+		for _, row := range section.result.Rows {
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(Map(row, func(v interface{}) string { return markdownCell(v) }), " | "))
+		}
+	}
+	if len(r.Charts) > 0 {
+		b.WriteString("\n## Charts\n")
+		for _, chart := range r.Charts {
+			var svg strings.Builder
+			if err := chart.RenderSVG(&svg); err != nil {
+				return err
+			}
+			encoded := base64.StdEncoding.EncodeToString([]byte(svg.String()))
+			fmt.Fprintf(&b, "\n![%s](data:%s;base64,%s)\n", markdownEscaper.Replace(chart.Title), contentTypeSVG, encoded)
+		}
+	}
+	_, err := io.WriteString(writer, b.String())
+	return err
+}
+
+func (r *Report) WriteHTML(writer io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(r.Title))
+	b.WriteString("<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse;margin-bottom:1em}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left}th{background:#f0f0f0}</style>\n")
+	b.WriteString("</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(r.Title))
+	fmt.Fprintf(&b, "<ul>\n<li>Environment: <code>%s</code></li>\n<li>Generated: %s</li>\n<li>Percentile window: %ds</li>\n<li>Topology interval: %s</li>\n</ul>\n", html.EscapeString(r.Environment), r.Generated.Format(time.RFC3339), r.Window, r.topologyInterval())
+	for _, section := range r.sections() {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(section.title))
+		if len(section.result.Rows) == 0 {
+			b.WriteString("<p>None</p>\n")
+			continue
+		}
+		b.WriteString("<table>\n<tr>")
+		for _, header := range section.result.Header {
+			fmt.Fprintf(&b, "<th>%s</th>", html.EscapeString(header))
+		}
+		b.WriteString("</tr>\n")
+		for _, row := range section.result.Rows {
+			b.WriteString("<tr>")
+			for _, value := range row {
+				fmt.Fprintf(&b, "<td>%s</td>", html.EscapeString(toString(value)))
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</table>\n")
+	}
+	if len(r.Charts) > 0 {
+		b.WriteString("<h2>Charts</h2>\n")
+		for _, chart := range r.Charts {
+			b.WriteString("<div>\n")
+			if err := chart.RenderSVG(&b); err != nil {
+				return err
+			}
+			b.WriteString("</div>\n")
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(writer, b.String())
+	return err
+}
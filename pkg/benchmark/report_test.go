@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func mockReport(t *testing.T) *Report {
+	workload := NewWorkload(&TestSessionMaker{})
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		workload.results.Add("read", "abc", int64(10+i))
+		workload.results.Add("write", "abc", int64(100+i))
+	}
+	workload.addEvent("abc", "write:error", "Leader switch | in progress")
+	workload.addEvent("abc", "write:error", "No leader")
+	workload.addEvent("abc", "note", "see [runbook](https://example.com)")
+	workload.addEvent("abc", "leader-switch", "core-1:7687 -> core-2:7687")
+
+	report, err := NewReport("testenv", workload, 5)
+	assert.Nil(t, err)
+	return report
+}
+
+func Test_ReportMarkdown(t *testing.T) {
+	report := mockReport(t)
+	assert.Equal(t, 3, len(report.Charts))
+
+	var markdown strings.Builder
+	err := report.WriteMarkdown(&markdown)
+	assert.Nil(t, err)
+	text := markdown.String()
+	assert.True(t, strings.HasPrefix(text, "# Latency benchmark report for testenv\n"))
+	assert.Contains(t, text, "| dbid | verb | count | errors | min | p50 | p90 | p99 | max |")
+	assert.Contains(t, text, "| abc | read | 10 | 0 | 10 | 14 | 18 | 19 | 19 |")
+	assert.Contains(t, text, "| abc | write | 10 | 2 | 100 | 104 | 108 | 109 | 109 |")
+	assert.Contains(t, text, "| abc | write:error | 2 | 2 | 3 | No leader |")
+	assert.Contains(t, text, "Leader switch \\| in progress")
+	assert.Contains(t, text, "see \\[runbook\\](https://example.com)")
+	assert.Contains(t, text, "- Percentile window: 5s\n- Topology interval: off\n")
+	assert.Contains(t, text, "## Configuration\n\n| dbid | workload | read rate | write rate | lag rate | causal rate | contention | auto-commit |")
+	assert.Contains(t, text, "| abc | default | 1 | 1 | 0 | 0 |  | false |")
+	assert.Equal(t, 3, strings.Count(text, "(data:image/svg+xml;base64,"))
+	assert.NotContains(t, text, "secret")
+}
+
+func Test_ReportHTML(t *testing.T) {
+	report := mockReport(t)
+
+	var page strings.Builder
+	err := report.WriteHTML(&page)
+	assert.Nil(t, err)
+	text := page.String()
+	assert.True(t, strings.HasPrefix(text, "<!DOCTYPE html>\n"))
+	assert.True(t, strings.HasSuffix(text, "</html>\n"))
+	assert.Contains(t, text, "<h2>Latency percentiles (ms)</h2>")
+	assert.Contains(t, text, "<h2>Configuration</h2>")
+	assert.Contains(t, text, "<td>Leader switch | in progress</td>")
+	assert.Equal(t, 3, strings.Count(text, "<svg "))
+	assert.Contains(t, text, ">leader-switch abc</text>")
+	assert.NotContains(t, text, ">write:error abc</text>")
+	assert.NotContains(t, text, ">note abc</text>")
+	assert.NotContains(t, text, "secret")
+}
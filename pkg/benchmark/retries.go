@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"strings"
+	"sync"
+)
+
+// The driver runs the transaction function of a query again after transient errors, like deadlocks or a leader that
+// stepped down, until it succeeds or the retry time runs out. A query that took 4 seconds may therefore have been
+// three failed attempts and a fast one. Each attempt is timed from the start of the transaction function until the
+// next attempt starts, or until it returns for the last one, and the failed ones are sent as '<verb>:retry' messages
+// with their duration and error code, while the number of attempts is kept with the timing of each sample. When the
+// driver gives up, the attempts before the last one are sent as retries too, and the last one as the error. Jobs can
+// also run their queries in auto-commit transactions, which the driver does not retry, so that transient errors are
+// reported as errors instead.
+
+// One run of the transaction function, where the error is empty for the attempt that succeeded, and 'commit' if the
+// function succeeded but the commit failed
+type Attempt struct {
+	Duration int64
+	Error    string
+}
+
+const commitFailed = "commit"
+
+// The error of a query in a transaction function, with all attempts of the driver, the last of which failed with it
+type retriedError struct {
+	err      error
+	attempts []Attempt
+}
+
+func (e *retriedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retriedError) Unwrap() error {
+	return e.err
+}
+
+// Run the query in a transaction function, or in an auto-commit transaction if the profile of the job says so
+func (n *Neo4jJob) run(runner QuerySession, accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	if n.profile.AutoCommit {
+		return runner.RunAutoCommitQuery(accessMode, query)
+	}
+	return runner.RunCypherQuery(accessMode, query)
+}
+
+// Send the failed attempts before the result to the channel
+func (n *Neo4jJob) sendRetries(ch chan Message, verb string, result *Neo4jResult) {
+	n.sendAttempts(ch, verb, result.attempts, result.server)
+}
+
+// Send the failed attempts before the one that failed with the error to the channel, if the driver retried the query
+func (n *Neo4jJob) sendFailedRetries(ch chan Message, verb string, err error) {
+	var retried *retriedError
+	if errors.As(err, &retried) {
+		n.sendAttempts(ch, verb, retried.attempts, Member{})
+	}
+}
+
+func (n *Neo4jJob) sendAttempts(ch chan Message, verb string, attempts []Attempt, server Member) {
+	for i := 0; i < len(attempts)-1; i++ {
+		ch <- Message{verb: verb + ":retry", dbid: n.dbid, value: attempts[i].Duration, message: attempts[i].Error, server: server}
+	}
+}
+
+// The failed attempts of the queries of one kind of one job
+type RetryCounts struct {
+	Retries   int
+	Deadlocks int
+	Time      int64 // the total duration of the failed attempts
+}
+
+// The attempts that the driver retried, by error code, for each kind of query of each job
+type Retries struct {
+	lock   sync.Mutex
+	counts map[string]map[string]RetryCounts
+}
+
+func NewRetries() *Retries {
+	return &Retries{counts: map[string]map[string]RetryCounts{}}
+}
+
+func (r *Retries) Add(verb string, dbid string, code string, duration int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := fmt.Sprintf("%s:%s", verb, dbid)
+	if r.counts[key] == nil {
+		r.counts[key] = map[string]RetryCounts{}
+	}
+	counts := r.counts[key][code]
+	counts.Retries++
+	counts.Time += duration
+	if strings.Contains(code, "DeadlockDetected") {
+		counts.Deadlocks++
+	}
+	r.counts[key][code] = counts
+}
+
+func (r *Retries) For(dbid string, verb string) RetryCounts {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	total := RetryCounts{}
+	for _, counts := range r.counts[fmt.Sprintf("%s:%s", verb, dbid)] {
+		total.Retries += counts.Retries
+		total.Deadlocks += counts.Deadlocks
+		total.Time += counts.Time
+	}
+	return total
+}
+
+func (r *Retries) Clear() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.counts = map[string]map[string]RetryCounts{}
+}
+
+// The number of attempts of each query of the job that was run by the driver
+func (w *Workload) AttemptsFor(dbid string, verb string) (*Neo4jResult, error) {
+	if verb != "read" && verb != "write" && verb != "contention" {
+		return nil, newWorkloadError(ErrInvalid, "Invalid result verb: %s", verb)
+	}
+	result := NewNeo4jResult([]string{"timestamp", "duration", "attempts"})
+	results := w.results.For(dbid, verb)
+	for i, timing := range results.timings {
+		if timing.Attempts > 0 {
+			result.add([]interface{}{results.timestamps[i], results.durations[i], timing.Attempts})
+		}
+	}
+	return result, nil
+}
+
+// How many queries of each job needed more than one attempt, and how much time the failed attempts took
+func (w *Workload) RetrySummary() *Neo4jResult {
+	result := NewNeo4jResult([]string{"dbid", "verb", "count", "retried", "max attempts", "retries", "deadlocks", "retry time"})
+	for _, client := range w.List() {
+		for _, verb := range []string{"read", "write", "contention"} {
+			count, retried, max := 0, 0, 0
+			for _, timing := range w.results.For(client.dbid, verb).timings {
+				if timing.Attempts > 0 {
+					count++
+				}
+				if timing.Attempts > 1 {
+					retried++
+				}
+				if timing.Attempts > max {
+					max = timing.Attempts
+				}
+			}
+			retries := w.retries.For(client.dbid, verb)
+			if count == 0 && retries.Retries == 0 {
+				continue
+			}
+			result.add([]interface{}{client.dbid, verb, count, retried, max, retries.Retries, retries.Deadlocks, retries.Time})
+		}
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package benchmark
+
+import (
+	"errors"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Remembers whether the queries were run in transaction functions or in auto-commit transactions
+type TestAutoCommitSession struct {
+	TestQuerySession
+	autoCommit []bool
+}
+
+func (r *TestAutoCommitSession) RunCypherQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	r.autoCommit = append(r.autoCommit, false)
+	result := NewNeo4jResult([]string{"value"})
+	result.attempts = []Attempt{{20, "Neo.TransientError.Transaction.DeadlockDetected"}, {10, commitFailed}, {5, ""}}
+	return result, nil
+}
+
+func (r *TestAutoCommitSession) RunAutoCommitQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	r.autoCommit = append(r.autoCommit, true)
+	result := NewNeo4jResult([]string{"value"})
+	result.attempts = []Attempt{{5, ""}}
+	return result, nil
+}
+
+func Test_RunAutoCommit(t *testing.T) {
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	session := &TestAutoCommitSession{}
+	ch := make(chan Message, 10)
+
+	result, err := job.run(session, neo4j.AccessModeWrite, "RETURN 1")
+	assert.Nil(t, err)
+	job.sendRetries(ch, "write", result)
+	job.profile.AutoCommit = true
+	result, err = job.run(session, neo4j.AccessModeWrite, "RETURN 1")
+	assert.Nil(t, err)
+	job.sendRetries(ch, "write", result)
+	close(ch)
+
+	assert.Equal(t, []bool{false, true}, session.autoCommit)
+	messages := []Message{}
+	for msg := range ch {
+		messages = append(messages, msg)
+	}
+	assert.Equal(t, []Message{
+		{verb: "write:retry", dbid: "abc", value: 20, message: "Neo.TransientError.Transaction.DeadlockDetected"},
+		{verb: "write:retry", dbid: "abc", value: 10, message: commitFailed},
+	}, messages)
+}
+
+func Test_SendFailedRetries(t *testing.T) {
+	job := NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret"))
+	ch := make(chan Message, 10)
+
+	deadlock := errors.New("Neo4jError: Neo.TransientError.Transaction.DeadlockDetected (deadlock)")
+	err := error(&retriedError{deadlock, []Attempt{{20, "Neo.TransientError.Transaction.DeadlockDetected"}, {10, commitFailed}, {5, "Neo.TransientError.Transaction.DeadlockDetected"}}})
+	assert.Equal(t, deadlock.Error(), err.Error())
+	assert.True(t, errors.Is(err, deadlock))
+	job.sendFailedRetries(ch, "contention", err)
+	job.sendFailedRetries(ch, "contention", deadlock)
+	close(ch)
+
+	messages := []Message{}
+	for msg := range ch {
+		messages = append(messages, msg)
+	}
+	assert.Equal(t, []Message{
+		{verb: "contention:retry", dbid: "abc", value: 20, message: "Neo.TransientError.Transaction.DeadlockDetected"},
+		{verb: "contention:retry", dbid: "abc", value: 10, message: commitFailed},
+	}, messages)
+}
+
+func Test_Retries(t *testing.T) {
+	retries := NewRetries()
+	retries.Add("write", "abc", "Neo.TransientError.Transaction.DeadlockDetected", 20)
+	retries.Add("write", "abc", "Neo.TransientError.Transaction.DeadlockDetected", 30)
+	retries.Add("write", "abc", "Neo.ClientError.Cluster.NotALeader", 5)
+	retries.Add("read", "abc", commitFailed, 7)
+	assert.Equal(t, RetryCounts{3, 2, 55}, retries.For("abc", "write"))
+	assert.Equal(t, RetryCounts{1, 0, 7}, retries.For("abc", "read"))
+	assert.Equal(t, RetryCounts{}, retries.For("xyz", "write"))
+
+	retries.Clear()
+	assert.Equal(t, RetryCounts{}, retries.For("abc", "write"))
+}
+
+func Test_Attempts(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := NewWorkload(&TestSessionMaker{})
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	workload.record(Message{verb: "read", dbid: "abc", value: 10, timing: Timing{Connection: 1, Server: 5, Streaming: 1, Client: 3, Attempts: 1, measured: true}})
+	workload.record(Message{verb: "write:retry", dbid: "abc", value: 20, message: "Neo.TransientError.Transaction.DeadlockDetected"})
+	workload.record(Message{verb: "write:retry", dbid: "abc", value: 10, message: commitFailed})
+	workload.record(Message{verb: "write", dbid: "abc", value: 40, timing: Timing{Connection: 1, Server: 5, Streaming: 1, Client: 3, Attempts: 3, measured: true}})
+	workload.record(Message{verb: "write", dbid: "abc", value: 12})
+
+	tests := []struct {
+		path       string
+		statuscode int
+		expected   string
+	}{
+		{"/stats/abc/read/attempts", http.StatusOK, `{"Header":["timestamp","duration","attempts"],"Rows":[[1,10,1]]}`},
+		{"/stats/abc/write/attempts", http.StatusOK, `{"Header":["timestamp","duration","attempts"],"Rows":[[2,40,3]]}`},
+		{"/stats/abc/other/attempts", http.StatusBadRequest, `{"error":"Invalid result verb: other","message":"Failed to get results"}`},
+		{apiPrefix + "/stats/abc/write/attempts", http.StatusOK, `{"Header":["timestamp","duration","attempts"],"Rows":[[2,40,3]]}`},
+		{apiPrefix + "/stats/xyz/write/attempts", http.StatusNotFound, `{"error":{"status":404,"message":"Failed to get results","detail":"Could not find client for database 'xyz'"}}`},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		if strings.HasPrefix(test.path, apiPrefix) {
+			s.apiHandler(workload)(recorder, mockRequest(test.path, nil))
+		} else {
+			s.resultsHandler(workload)(recorder, mockRequest(test.path, nil))
+		}
+		assert.Equal(t, test.statuscode, recorder.Code, test.path)
+		assert.Equal(t, test.expected, recorder.Body.String(), test.path)
+	}
+
+	assert.Equal(t, [][]interface{}{
+		{"abc", "read", 1, 0, 1, 0, 0, int64(0)},
+		{"abc", "write", 1, 1, 3, 2, 1, int64(30)},
+	}, workload.RetrySummary().Rows)
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	var markdown strings.Builder
+	assert.Nil(t, report.WriteMarkdown(&markdown))
+	assert.Contains(t, markdown.String(), "## Retries in transaction functions (ms)\n\n| dbid | verb | count | retried | max attempts | retries | deadlocks | retry time |")
+	assert.Contains(t, markdown.String(), "| abc | write | 1 | 1 | 3 | 2 | 1 | 30 |")
+}
@@ -2,17 +2,34 @@ package benchmark
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Server struct {
-	environment string // for constructing database access URI
-	listenPort  int    // The client benchmark server will listen on this port for REST requests
+	environment   string           // name of the environment being benchmarked, may be empty
+	addresses     AddressStrategy  // for constructing database access URI from the dbid
+	listenPort    int              // The client benchmark server will listen on this port for REST requests
+	authenticator Authenticator    // for authenticating requests to the server, not the Neo4j databases
+	credentials   *CredentialStore // optional server-side Neo4j credentials, nil if not configured
+	config        *Config          // databases and workloads to configure at startup, empty if there is no CONFIG_FILE
+	configPath    string           // for reloading the configuration, empty if there is no CONFIG_FILE
+	configured    map[string]configuredJob
+	configLock    *sync.Mutex
+	discovery     *Discovery // finds databases in Kubernetes, nil unless DISCOVERY_INTERVAL is positive
+	publisher     *Publisher // publishes results to the discovered databases, nil unless PUBLISH_INTERVAL is positive
+	ready         *int32     // set to one once the configuration has been applied at startup
+	reportPath    string     // where to write the final report on shutdown, empty if there is no REPORT_PATH
+	shutdown      time.Duration
+	topology      time.Duration // how often each job polls the topology of its cluster, zero to disable
+	done          chan struct{} // closed on shutdown, to stop the goroutines running in the background
 }
 
 const (
@@ -20,6 +37,9 @@ const (
 	contentTypeHTML = "text/html"
 	contentTypeText = "text/plain"
 	contentTypeJSON = "application/json"
+	contentTypeSVG  = "image/svg+xml"
+	contentTypeMD   = "text/markdown"
+	contentTypeYAML = "application/yaml"
 )
 
 func mustReadEnv(key string) string {
@@ -39,13 +59,103 @@ func mustReadEnvAsInt(key string) int {
 	return integer
 }
 
+// The value of the environment variable, or the fallback from the configuration file if it is not set
+func readEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
 func NewServer() *Server {
-	listen_port := mustReadEnvAsInt("LISTEN_PORT")
-	environment := mustReadEnv("ENVIRONMENT")
+	config := &Config{}
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile != "" {
+		loaded, err := LoadConfig(configFile)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to load configuration from %q: %v", configFile, err))
+		}
+		config = loaded
+	}
+	listen_port := config.ListenPort
+	if _, ok := os.LookupEnv("LISTEN_PORT"); ok || listen_port == 0 {
+		listen_port = mustReadEnvAsInt("LISTEN_PORT")
+	}
+	environment := readEnv("ENVIRONMENT", config.Environment)
 	if environment == "production" {
 		panic(fmt.Sprintf("This service puts a read and write load on databases - and is therefor disabled for production environments"))
 	}
-	return &Server{environment, listen_port}
+	var addresses AddressStrategy = &ExplicitAddressStrategy{}
+	if template := readEnv("ADDRESS_TEMPLATE", config.AddressTemplate); template != "" {
+		strategy, err := NewTemplateAddressStrategy(template)
+		if err != nil {
+			panic(fmt.Sprintf("%q environment variable not a valid address template: %v", "ADDRESS_TEMPLATE", err))
+		}
+		addresses = strategy
+	} else if environment != "" {
+		addresses = &AuraAddressStrategy{environment}
+	}
+	log.Printf("Using %s for database addresses", addresses)
+	var authenticator Authenticator = &BasicAuthPresenceAuthenticator{}
+	if authFile := readEnv("AUTH_FILE", config.AuthFile); authFile != "" {
+		tokens, err := LoadTokenFile(authFile)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to load authentication file %q: %v", authFile, err))
+		}
+		log.Printf("Loaded %d users and tokens from %s", len(tokens.credentials), authFile)
+		authenticator = tokens
+	}
+	var credentials *CredentialStore
+	if credentialsPath := readEnv("CREDENTIALS_PATH", config.CredentialsPath); credentialsPath != "" {
+		store, err := NewCredentialStore(credentialsPath)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to load credentials from %q: %v", credentialsPath, err))
+		}
+		log.Printf("Loaded %d credentials from %s", len(store.Names()), credentialsPath)
+		credentials = store
+	}
+	var discovery *Discovery
+	var publisher *Publisher
+	// both are off unless set to a positive number of seconds, as discovery only works inside the Aura environment
+	interval, publishInterval := 0, 0
+	if _, ok := os.LookupEnv("DISCOVERY_INTERVAL"); ok {
+		interval = mustReadEnvAsInt("DISCOVERY_INTERVAL")
+	}
+	if _, ok := os.LookupEnv("PUBLISH_INTERVAL"); ok {
+		publishInterval = mustReadEnvAsInt("PUBLISH_INTERVAL")
+	}
+	if interval > 0 {
+		onlyChaosEnabled, err := strconv.ParseBool(readEnv("ONLY_DATABASES_WITH_CHAOS_ENABLED", "false"))
+		if err != nil {
+			panic(fmt.Sprintf("%q environment variable not a valid boolean: %v", "ONLY_DATABASES_WITH_CHAOS_ENABLED", err))
+		}
+		lister, err := NewInClusterClient(readEnv("NEO4JDATABASES_API_VERSION", "v1"))
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create Kubernetes client for discovery: %v", err))
+		}
+		log.Printf("Discovering databases every %d seconds (only chaos enabled: %v)", interval, onlyChaosEnabled)
+		discovery = NewDiscovery(lister, time.Duration(interval)*time.Second, onlyChaosEnabled)
+		if publishInterval > 0 {
+			window := 60
+			if _, ok := os.LookupEnv("PUBLISH_WINDOW"); ok {
+				window = mustReadEnvAsInt("PUBLISH_WINDOW")
+			}
+			log.Printf("Publishing results to discovered databases every %d seconds", publishInterval)
+			publisher = NewPublisher(lister, time.Duration(publishInterval)*time.Second, int64(window))
+		}
+	} else if publishInterval > 0 {
+		panic(fmt.Sprintf("%q environment variable requires DISCOVERY_INTERVAL to be set", "PUBLISH_INTERVAL"))
+	}
+	shutdown := defaultShutdownTimeout
+	if _, ok := os.LookupEnv("SHUTDOWN_TIMEOUT"); ok {
+		shutdown = time.Duration(mustReadEnvAsInt("SHUTDOWN_TIMEOUT")) * time.Second
+	}
+	reportPath := readEnv("REPORT_PATH", config.ReportPath)
+	topology := defaultTopologyInterval
+	if _, ok := os.LookupEnv("TOPOLOGY_INTERVAL"); ok {
+		topology = time.Duration(mustReadEnvAsInt("TOPOLOGY_INTERVAL")) * time.Second
+	}
+	return &Server{environment, addresses, listen_port, authenticator, credentials, config, configFile, map[string]configuredJob{}, &sync.Mutex{}, discovery, publisher, new(int32), reportPath, shutdown, topology, make(chan struct{})}
 }
 
 func (s *Server) handleStringResult(writer http.ResponseWriter, result string, err error, iferr string) {
@@ -69,7 +179,7 @@ func resultToJson(text string) ([]byte, error) {
 }
 
 func makeNeo4jClientResult(clients []*Neo4jJob, workload *Workload) (*Neo4jResult, error) {
-	result := NewNeo4jResult([]string{"name", "address", "running", "read", "write"})
+	result := NewNeo4jResult([]string{"name", "address", "database", "running", "read", "write"})
 	for _, client := range clients {
 		read, err := workload.CountsFor(client.dbid, "read")
 		if err != nil {
@@ -79,7 +189,7 @@ func makeNeo4jClientResult(clients []*Neo4jJob, workload *Workload) (*Neo4jResul
 		if err != nil {
 			log.Printf("Failed to get write results for %s: %v", client.dbid, err)
 		}
-		result.add([]interface{}{client.dbid, client.neo4j.neo4jAddress, client.running, read, write})
+		result.add([]interface{}{client.dbid, client.neo4j.neo4jAddress, client.neo4j.database, client.isRunning(), read, write})
 	}
 	return result, nil
 }
@@ -131,157 +241,279 @@ func (s *Server) writeError(writer http.ResponseWriter, message string) {
 }
 
 func (s *Server) writeErrorMessage(writer http.ResponseWriter, message string, err error) {
+	s.writeErrorStatus(writer, http.StatusBadRequest, message, err)
+}
+
+func (s *Server) writeErrorStatus(writer http.ResponseWriter, status int, message string, err error) {
 	data := map[string]string{}
 	data["message"] = message
 	if err != nil {
 		data["error"] = err.Error()
 	}
-	writer.WriteHeader(http.StatusBadRequest)
 	msgBytes, err2 := json.Marshal(data)
 	if err2 == nil {
 		writer.Header().Set(contentType, contentTypeJSON)
+	}
+	writer.WriteHeader(status)
+	if err2 == nil {
 		writer.Write(msgBytes)
 	}
 }
 
 func (s *Server) indexHandler() http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
+		commands := helpCommands()
+		width := 0
+		for _, command := range commands {
+			if len(command[0]) > width {
+				width = len(command[0])
+			}
+		}
 		writer.Header().Set(contentType, contentTypeText)
 		fmt.Fprintf(writer, "Commands available for benchmark:\n")
-		fmt.Fprintf(writer, "    /                    - show commands\n")
-		fmt.Fprintf(writer, "    /neo4j/add/<DBID>    - add workload for database\n")
-		fmt.Fprintf(writer, "    /neo4j/remove/<DBID> - add workload for database\n")
-		fmt.Fprintf(writer, "    /neo4j/list          - list current database workloads\n")
-		fmt.Fprintf(writer, "    /start               - start benchmark\n")
-		fmt.Fprintf(writer, "    /stop                - stop benchmark\n")
-		fmt.Fprintf(writer, "    /results             - get current results\n")
+		for _, command := range commands {
+			fmt.Fprintf(writer, "    %-*s - %s\n", width, command[0], command[1])
+		}
 	}
 }
 
-func (s *Server) makeAddress(dbid string) string {
-	return fmt.Sprintf("neo4j+s://%s-%s.databases.neo4j.io", dbid, s.environment)
+func (s *Server) makeAddress(dbid string) (string, error) {
+	return s.addresses.Address(dbid)
 }
 
 func (s *Server) neo4jHandler(workload *Workload) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		username, password, ok := request.BasicAuth()
-		if !ok {
-			s.writeError(writer, "No basic authentication information provided")
-		} else {
-			parts := strings.Split(request.URL.Path, "/")
-			switch len(parts) {
-			case 3:
-				verb := parts[2]
-				switch verb {
-				case "list":
-					s.handleNeo4jResults(writer, workload.List(), workload, nil, "")
-				default:
-					s.invalidPath(writer, parts[1], request.URL.Path)
+		parts := strings.Split(request.URL.Path, "/")
+		switch len(parts) {
+		case 3:
+			verb := parts[2]
+			switch verb {
+			case "list":
+				s.handleNeo4jResults(writer, workload.List(), workload, nil, "")
+			default:
+				s.invalidPath(writer, parts[1], request.URL.Path)
+			}
+		case 4:
+			verb := parts[2]
+			dbid := parts[3]
+			switch verb {
+			case "add":
+				query := request.URL.Query()
+				neo4j_job, err := s.newNeo4jJob(JobSpec{Dbid: dbid, URI: query.Get("uri"), Database: query.Get("database"), Credentials: query.Get("credentials")}, request)
+				if err != nil {
+					s.writeErrorMessage(writer, "Invalid database", err)
+					return
 				}
-			case 4:
-				verb := parts[2]
-				dbid := parts[3]
-				address := s.makeAddress(dbid)
-				neo4j := NewNeo4j(dbid, address, username, password)
-				neo4j_job := NewNeo4jJob(*neo4j)
-				switch verb {
-				case "add":
-					err := workload.Add(neo4j_job)
-					s.handleNeo4jResult(writer, neo4j_job, workload, err, "Failed to add workload for neo4j database")
-
-				case "remove":
-					err := workload.Remove(neo4j_job)
-					s.handleNeo4jResult(writer, neo4j_job, workload, err, "Failed to remove workload for neo4j database")
-				case "show":
-					err, found := workload.Find(neo4j_job)
-					s.handleNeo4jResult(writer, found, workload, err, "Failed show workload for neo4j database")
-				default:
-					s.invalidPath(writer, parts[1], request.URL.Path)
+				err = workload.Add(neo4j_job)
+				if errors.Is(err, ErrConflict) {
+					// adding a database again used to succeed, so clients of this endpoint get the existing job
+					err, neo4j_job = workload.Find(neo4j_job)
 				}
+				s.handleNeo4jResult(writer, neo4j_job, workload, err, "Failed to add workload for neo4j database")
+
+			case "remove":
+				// the job is looked up by its dbid, as its address may not be derivable from the dbid
+				found, err := s.findJob(workload, dbid)
+				if err == nil {
+					err = workload.Remove(found)
+				}
+				s.handleNeo4jResult(writer, found, workload, err, "Failed to remove workload for neo4j database")
+			case "show":
+				found, err := s.findJob(workload, dbid)
+				s.handleNeo4jResult(writer, found, workload, err, "Failed show workload for neo4j database")
 			default:
 				s.invalidPath(writer, parts[1], request.URL.Path)
 			}
+		default:
+			s.invalidPath(writer, parts[1], request.URL.Path)
 		}
 	}
 }
 
 func (s *Server) startHandler(workload *Workload) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		_, _, ok := request.BasicAuth()
-		// TODO: actually authenticate
-		if !ok {
-			s.writeError(writer, "No basic authentication information provided")
-		} else {
-			result, err := workload.Start()
-			s.handleStringResult(writer, result, err, "Failed to start workload")
-		}
+		result, err := workload.Start()
+		s.handleStringResult(writer, result, err, "Failed to start workload")
 	}
 }
 
 func (s *Server) stopHandler(workload *Workload) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		_, _, ok := request.BasicAuth()
-		if !ok {
-			s.writeError(writer, "No basic authentication information provided")
-		} else {
-			result, err := workload.Stop()
-			s.handleStringResult(writer, result, err, "Failed to stop workload")
-		}
+		result, err := workload.Stop()
+		s.handleStringResult(writer, result, err, "Failed to stop workload")
 	}
 }
 
 func (s *Server) waitHandler(workload *Workload) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		_, _, ok := request.BasicAuth()
-		if !ok {
-			s.writeError(writer, "No basic authentication information provided")
-		} else {
-			parts := strings.Split(request.URL.Path, "/")
-			switch len(parts) {
-			case 3:
-				threshold, err := strconv.ParseInt(parts[2], 10, 32)
-				if err != nil {
-					s.writeErrorMessage(writer, "Failed to parse threshold as integer", err)
-				} else {
-					result, err := workload.WaitForAtLeast(int(threshold))
-					s.handleStringResult(writer, result, err, "Failed to wait for specified number of results")
-				}
-			default:
-				s.invalidPath(writer, parts[1], request.URL.Path)
+		parts := strings.Split(request.URL.Path, "/")
+		switch len(parts) {
+		case 3:
+			threshold, err := strconv.ParseInt(parts[2], 10, 32)
+			if err != nil {
+				s.writeErrorMessage(writer, "Failed to parse threshold as integer", err)
+			} else {
+				result, err := workload.WaitForAtLeast(int(threshold))
+				s.handleStringResult(writer, result, err, "Failed to wait for specified number of results")
 			}
+		default:
+			s.invalidPath(writer, parts[1], request.URL.Path)
 		}
 	}
 }
 
 func (s *Server) resultsHandler(workload *Workload) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
-		_, _, ok := request.BasicAuth()
-		if !ok {
-			s.writeError(writer, "No basic authentication information provided")
-		} else {
-			parts := strings.Split(request.URL.Path, "/")
-			switch len(parts) {
-			case 2:
-				result, err := workload.Results()
+		parts := strings.Split(request.URL.Path, "/")
+		switch len(parts) {
+		case 2:
+			result, err := workload.Results()
+			s.handleResult(writer, result, err, "Failed to get results")
+		case 3:
+			switch parts[2] {
+			case "table":
+				result, err := workload.ResultsTable()
 				s.handleResult(writer, result, err, "Failed to get results")
-			case 3:
-				switch parts[2] {
-				case "table":
-					result, err := workload.ResultsTable()
-					s.handleResult(writer, result, err, "Failed to get results")
-				default:
-					dbid := parts[2]
-					result, err := workload.ResultsFor(dbid, "read")
-					s.handleResult(writer, result, err, "Failed to get results")
-				}
-			case 4:
+			default:
 				dbid := parts[2]
-				verb := parts[3]
-				result, err := workload.ResultsFor(dbid, verb)
+				result, err := workload.ResultsFor(dbid, "read")
+				s.handleResult(writer, result, err, "Failed to get results")
+			}
+		case 4:
+			dbid := parts[2]
+			verb := parts[3]
+			result, err := workload.ResultsFor(dbid, verb)
+			s.handleResult(writer, result, err, "Failed to get results")
+		case 5:
+			switch parts[4] {
+			case "members":
+				result, err := workload.MemberSummary(parts[2], parts[3])
+				s.handleResult(writer, result, err, "Failed to get results")
+			case "breakdown":
+				result, err := workload.BreakdownFor(parts[2], parts[3])
+				s.handleResult(writer, result, err, "Failed to get results")
+			case "attempts":
+				result, err := workload.AttemptsFor(parts[2], parts[3])
 				s.handleResult(writer, result, err, "Failed to get results")
 			default:
 				s.invalidPath(writer, parts[1], request.URL.Path)
 			}
+		default:
+			s.invalidPath(writer, parts[1], request.URL.Path)
+		}
+	}
+}
+
+func queryInt(request *http.Request, key string, defaultValue int64) (int64, error) {
+	value := request.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func queryFloat(request *http.Request, key string, defaultValue float64) (float64, error) {
+	value := request.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func queryBool(request *http.Request, key string) bool {
+	value, err := strconv.ParseBool(request.URL.Query().Get(key))
+	return err == nil && value
+}
+
+func (s *Server) handleChart(writer http.ResponseWriter, title string, result *Neo4jResult, annotations []ChartAnnotation, logScale bool, err error, iferr string) {
+	if err != nil {
+		s.writeErrorMessage(writer, iferr, err)
+	} else {
+		chart, err := NewChartFromResult(title, "duration (ms)", logScale, result)
+		if err != nil {
+			s.writeErrorMessage(writer, iferr, err)
+		} else {
+			chart.Annotations = append(chart.Annotations, annotations...)
+			writer.Header().Set(contentType, contentTypeSVG)
+			chart.RenderSVG(writer)
+		}
+	}
+}
+
+func (s *Server) chartsHandler(workload *Workload) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		logScale := queryBool(request, "log")
+		window, err := queryInt(request, "window", 10)
+		if err != nil {
+			s.writeErrorMessage(writer, "Failed to parse window as integer", err)
+			return
+		}
+		parts := strings.Split(request.URL.Path, "/")
+		switch len(parts) {
+		case 3:
+			switch parts[2] {
+			case "table":
+				result, err := workload.ResultsTable()
+				s.handleChart(writer, "Latency", result, nil, logScale, err, "Failed to chart results")
+			case "percentiles":
+				p, err := queryFloat(request, "percentile", 99)
+				if err != nil {
+					s.writeErrorMessage(writer, "Failed to parse percentile as number", err)
+				} else {
+					result, err := workload.PercentilesTable(p, window)
+					title := fmt.Sprintf("p%v latency per %ds window", p, window)
+					s.handleChart(writer, title, result, nil, logScale, err, "Failed to chart percentiles")
+				}
+			default:
+				s.invalidPath(writer, parts[1], request.URL.Path)
+			}
+		case 4:
+			dbid := parts[2]
+			verb := parts[3]
+			result, err := workload.PercentilesFor(dbid, verb, window)
+			title := fmt.Sprintf("%s latency percentiles for %s per %ds window", verb, dbid, window)
+			var annotations []ChartAnnotation
+			if client, findErr := s.findJob(workload, dbid); findErr == nil && verb == "write" {
+				annotations = workload.leaderSwitchAnnotations(client)
+			}
+			s.handleChart(writer, title, result, annotations, logScale, err, "Failed to chart percentiles")
+		case 5:
+			if parts[4] != "breakdown" {
+				s.invalidPath(writer, parts[1], request.URL.Path)
+				return
+			}
+			result, err := workload.BreakdownFor(parts[2], parts[3])
+			s.handleChart(writer, breakdownTitle(parts[2], parts[3]), result, nil, logScale, err, "Failed to chart breakdown")
+		default:
+			s.invalidPath(writer, parts[1], request.URL.Path)
+		}
+	}
+}
+
+func (s *Server) reportHandler(workload *Workload) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		window, err := queryInt(request, "window", 10)
+		if err != nil {
+			s.writeErrorMessage(writer, "Failed to parse window as integer", err)
+			return
+		}
+		report, err := NewReport(s.environment, workload, window)
+		if err != nil {
+			s.writeErrorMessage(writer, "Failed to create report", err)
+			return
+		}
+		format := request.URL.Query().Get("format")
+		switch format {
+		case "", "html":
+			writer.Header().Set(contentType, contentTypeHTML)
+			writer.Header().Set("Content-Disposition", `attachment; filename="latency-report.html"`)
+			report.WriteHTML(writer)
+		case "markdown", "md":
+			writer.Header().Set(contentType, contentTypeMD)
+			writer.Header().Set("Content-Disposition", `attachment; filename="latency-report.md"`)
+			report.WriteMarkdown(writer)
+		default:
+			s.writeError(writer, fmt.Sprintf("Invalid report format: %s", format))
 		}
 	}
 }
@@ -292,17 +524,63 @@ func (s *Server) invalidRequestHandler(path string) http.HandlerFunc {
 	}
 }
 
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+func (s *Server) routes(workload *Workload) []route {
+	return []route{
+		{"/", s.indexHandler()},
+		{"/openapi.json", s.openAPIHandler()},
+		{"/healthz", s.healthzHandler(workload)},
+		{"/readyz", s.readyzHandler()},
+		{"/status", s.statusHandler(workload)},
+		{"/neo4j/", s.neo4jHandler(workload)},
+		{"/start", s.startHandler(workload)},
+		{"/stop", s.stopHandler(workload)},
+		{"/stats", s.resultsHandler(workload)},
+		{"/stats/", s.resultsHandler(workload)},
+		// registered as a subtree, since the pattern '/wait' only matches the path without a threshold, so that before
+		// this route was documented '/wait/<THRESHOLD>' was answered by the index handler as an invalid request
+		{"/wait/", s.waitHandler(workload)},
+		{"/charts/", s.chartsHandler(workload)},
+		{"/report", s.reportHandler(workload)},
+		{apiPrefix + "/", s.apiHandler(workload)},
+	}
+}
+
 func (s *Server) Run() {
-	sessionMaker := QuerySessionMaker{}
+	sessionMaker := QuerySessionMaker{s.credentials}
 	workload := NewWorkload(&sessionMaker)
+	workload.topologyInterval = s.topology
+	if s.credentials != nil {
+		go s.credentials.Watch(time.Minute, s.done, workload.RestartWithCredentials)
+	}
 	uri := fmt.Sprintf("0.0.0.0:%d", s.listenPort)
-	http.HandleFunc("/", s.indexHandler())
-	http.HandleFunc("/neo4j/", s.neo4jHandler(workload))
-	http.HandleFunc("/start", s.startHandler(workload))
-	http.HandleFunc("/stop", s.stopHandler(workload))
-	http.HandleFunc("/stats", s.resultsHandler(workload))
-	http.HandleFunc("/stats/", s.resultsHandler(workload))
-	http.HandleFunc("/wait", s.waitHandler(workload))
+	for _, route := range s.routes(workload) {
+		http.HandleFunc(route.pattern, s.authorize(route.handler))
+	}
+	go func() {
+		s.applyConfig(s.config, workload)
+		s.setReady()
+	}()
+	if s.discovery != nil {
+		go s.discovery.Run(s, workload, s.done)
+	}
+	if s.publisher != nil {
+		go s.publisher.Run(s.discovery, workload, s.done)
+	}
+	if s.configPath != "" {
+		go s.reloadOnSignal(workload)
+	}
+	server := &http.Server{Addr: uri}
+	finished := make(chan struct{})
+	go s.shutdownOnSignal(server, workload, finished)
 	// The certificates are generated by neo4j-init-sidecar which is run as an InitContainer before all normal containers
-	log.Fatal(http.ListenAndServe(uri, nil))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-finished
+	log.Printf("Shutdown complete")
 }
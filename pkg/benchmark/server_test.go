@@ -55,35 +55,54 @@ func Test_Server(t *testing.T) {
 	}{
 		{path: "/invalid", statuscode: http.StatusBadRequest, expected: `{"message":"Invalid request: /invalid"}`},
 		{path: "/", statuscode: http.StatusOK, expected: `Commands available for benchmark:
-    /                    - show commands
-    /neo4j/add/<DBID>    - add workload for database
-    /neo4j/remove/<DBID> - add workload for database
-    /neo4j/list          - list current database workloads
-    /start               - start benchmark
-    /stop                - stop benchmark
-    /results             - get current results
+    /                               - show commands
+    /openapi.json                   - OpenAPI specification of all routes
+    /healthz                        - liveness probe, fails if the workload is stuck
+    /readyz                         - readiness probe, fails until the configuration is applied
+    /status                         - show state of the workload and each job
+    /neo4j/add/<DBID>               - add workload for database
+    /neo4j/remove/<DBID>            - remove workload for database
+    /neo4j/show/<DBID>              - show workload for database
+    /neo4j/list                     - list current database workloads
+    /start                          - start benchmark
+    /stop                           - stop benchmark
+    /wait/<THRESHOLD>               - wait for a number of results
+    /stats                          - get result counts
+    /stats/table                    - get results of all databases as a table
+    /stats/<DBID>                   - get read results for database
+    /stats/<DBID>/<VERB>            - get results for database
+    /stats/<DBID>/<VERB>/members    - get results for database per cluster member
+    /stats/<DBID>/<VERB>/breakdown  - get results for database split into parts
+    /stats/<DBID>/<VERB>/attempts   - get attempts of each query for database
+    /charts/table                   - SVG chart of current results
+    /charts/percentiles             - SVG chart of percentiles
+    /charts/<DBID>/<VERB>           - SVG chart of percentiles for database
+    /charts/<DBID>/<VERB>/breakdown - SVG chart of results split into parts
+    /report                         - download HTML or Markdown report
+    /api/v1/                        - versioned REST API, see /openapi.json
 `},
 		{path: "/neo4j/add", statuscode: http.StatusBadRequest, expected: `{"message":"invalid path for 'neo4j' request: /neo4j/add"}`},
-		{path: "/neo4j/add/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io",false,0,0]]}`},
+		{path: "/neo4j/add/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
 		{path: "/neo4j/remove", statuscode: http.StatusBadRequest, expected: `{"message":"invalid path for 'neo4j' request: /neo4j/remove"}`},
-		{path: "/neo4j/remove/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[]}`},
-		{path: "/neo4j/add/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/add/xyz", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["xyz","neo4j+s://xyz-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/add/123", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io",false,0,0],["abc","neo4j+s://abc-testenv.databases.neo4j.io",false,0,0],["xyz","neo4j+s://xyz-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/remove/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io",false,0,0],["xyz","neo4j+s://xyz-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/remove/123", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io",false,0,0]]}`},
+		{path: "/neo4j/remove/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[]}`},
+		{path: "/neo4j/add/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/add/xyz", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["xyz","neo4j+s://xyz-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/add/123", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/add/123", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io","neo4j",false,0,0],["abc","neo4j+s://abc-testenv.databases.neo4j.io","neo4j",false,0,0],["xyz","neo4j+s://xyz-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/remove/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io","neo4j",false,0,0],["xyz","neo4j+s://xyz-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/remove/123", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["123","neo4j+s://123-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
 		{path: "/neo4j/remove/123", statuscode: http.StatusBadRequest, expected: `{"error":"Could not find client for database '123'","message":"Failed to remove workload for neo4j database"}`},
-		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["xyz","neo4j+s://xyz-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/remove/xyz", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["xyz","neo4j+s://xyz-testenv.databases.neo4j.io",false,0,0]]}`},
-		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[]}`},
+		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["xyz","neo4j+s://xyz-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/remove/xyz", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["xyz","neo4j+s://xyz-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
+		{path: "/neo4j/list", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[]}`},
 		{path: "/start", statuscode: http.StatusOK, expected: `{"result":"Started"}`},
 		{path: "/start", statuscode: http.StatusBadRequest, expected: `{"error":"Already started","message":"Failed to start workload"}`},
 		{path: "/stop", statuscode: http.StatusOK, expected: `{"result":"Stopped"}`},
 		{path: "/stop", statuscode: http.StatusBadRequest, expected: `{"error":"Already stopped","message":"Failed to stop workload"}`},
-		{path: "/neo4j/add/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io",false,0,0]]}`},
+		{path: "/neo4j/add/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","neo4j+s://abc-testenv.databases.neo4j.io","neo4j",false,0,0]]}`},
 		{path: "/start", statuscode: http.StatusOK, expected: `{"result":"Started"}`},
 		{path: "/wait/5", statuscode: http.StatusOK, expected: `{"result":"*?>=5*"}`},
 		{path: "/stop", statuscode: http.StatusOK, expected: `{"result":"Stopped"}`},
@@ -93,6 +112,10 @@ func Test_Server(t *testing.T) {
 		{path: "/stats/abc/write", statuscode: http.StatusOK, expected: `{"Header":["timestamp","duration"],"Rows":[[*?>0*,1000],[*?>1*,1000],[*?>2*,1000],***]}`},
 		{path: "/stats/abc/other", statuscode: http.StatusBadRequest, expected: `{"error":"Invalid result verb: other","message":"Failed to get results"}`},
 		{path: "/stats/table", statuscode: http.StatusOK, expected: `{"Header":["timestamp","read:abc","write:abc"],"Rows":[[1,*?>=1000*,*?>=1000*],[2,*?>=1000*,*?>=1000*],[3,*?>=1000*,*?>=1000*],***]}`},
+		{path: "/charts/abc/other", statuscode: http.StatusBadRequest, expected: `{"error":"Invalid result verb: other","message":"Failed to chart percentiles"}`},
+		{path: "/charts/abc/read?window=0", statuscode: http.StatusBadRequest, expected: `{"error":"Invalid percentile window: 0","message":"Failed to chart percentiles"}`},
+		{path: "/report?format=pdf", statuscode: http.StatusBadRequest, expected: `{"message":"Invalid report format: pdf"}`},
+		{path: "/charts/percentiles?percentile=101", statuscode: http.StatusBadRequest, expected: `{"error":"Invalid percentile: 101","message":"Failed to chart percentiles"}`},
 	}
 
 	for index, data := range tests {
@@ -115,6 +138,10 @@ func Test_Server(t *testing.T) {
 				handler = s.waitHandler(workload)
 			case "stats":
 				handler = s.resultsHandler(workload)
+			case "charts":
+				handler = s.chartsHandler(workload)
+			case "report":
+				handler = s.reportHandler(workload)
 			}
 			parameters := url.Values{}
 			println(fields)
@@ -140,6 +167,37 @@ func Test_Server(t *testing.T) {
 	}
 }
 
+func Test_LegacyRoutesWithExplicitAddresses(t *testing.T) {
+	s, workload := mockServer(t)
+	s.addresses = &ExplicitAddressStrategy{}
+	handler := s.neo4jHandler(workload)
+
+	tests := []struct {
+		path       string
+		parameters url.Values
+		statuscode int
+		expected   string
+	}{
+		{path: "/neo4j/add/abc", statuscode: http.StatusBadRequest, expected: `{"error":"No address configured for database 'abc', an explicit uri is required","message":"Invalid database"}`},
+		{path: "/neo4j/add/abc", parameters: url.Values{"uri": {"bolt://localhost:7687"}}, statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","bolt://localhost:7687","neo4j",false,0,0]]}`},
+		{path: "/neo4j/show/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","bolt://localhost:7687","neo4j",false,0,0]]}`},
+		{path: "/neo4j/remove/abc", statuscode: http.StatusOK, expected: `{"Header":["name","address","database","running","read","write"],"Rows":[["abc","bolt://localhost:7687","neo4j",false,0,0]]}`},
+		{path: "/neo4j/show/abc", statuscode: http.StatusBadRequest, expected: `{"error":"Could not find client for database 'abc'","message":"Failed show workload for neo4j database"}`},
+	}
+	for index, data := range tests {
+		t.Run(fmt.Sprintf("test_%d %s", index+1, data.path), func(t *testing.T) {
+			request := mockRequest(data.path, data.parameters)
+			request.SetBasicAuth("neo4j", "secret")
+			responseRecorder := httptest.NewRecorder()
+			handler(responseRecorder, request)
+			response := responseRecorder.Result()
+			body, _ := ioutil.ReadAll(response.Body)
+			assert.Equal(t, data.expected, string(body))
+			assert.Equal(t, data.statuscode, response.StatusCode)
+		})
+	}
+}
+
 func evalExpression(t *testing.T, value int64, prefix string, expression string, comparison func(a, b int64) bool) {
 	expected, err := strconv.ParseInt(expression[len(prefix):len(expression)], 10, 64)
 	if err != nil {
@@ -239,6 +297,15 @@ func (r *TestQuerySession) RunCypherQuery(accessMode neo4j.AccessMode, query str
 	return result, nil
 }
 
+func (r *TestQuerySession) RunCausalQuery(accessMode neo4j.AccessMode, query string, bookmark string) (*Neo4jResult, string, error) {
+	result, err := r.RunCypherQuery(accessMode, query)
+	return result, "", err
+}
+
+func (r *TestQuerySession) RunAutoCommitQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	return r.RunCypherQuery(accessMode, query)
+}
+
 func (r *TestQuerySession) Close() error {
 	return nil
 }
@@ -0,0 +1,78 @@
+package benchmark
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// On SIGTERM or SIGINT the jobs are stopped and their results recorded before the process exits, so that a pod being
+// deleted does not lose the end of a benchmark. When REPORT_PATH is set, the final report is written there, as
+// Markdown if the name ends in .md and as HTML otherwise. Everything must finish within SHUTDOWN_TIMEOUT seconds.
+
+const defaultShutdownTimeout = 20 * time.Second
+
+const finalReportWindow = 10
+
+func (s *Server) WriteReport(workload *Workload) error {
+	report, err := NewReport(s.environment, workload, finalReportWindow)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so that a report from an earlier run is never left half overwritten
+	temporary := s.reportPath + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(s.reportPath) == ".md" {
+		err = report.WriteMarkdown(file)
+	} else {
+		err = report.WriteHTML(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	return os.Rename(temporary, s.reportPath)
+}
+
+// Stop the jobs, write the final report and then stop serving requests, all before the shutdown deadline
+func (s *Server) Shutdown(server *http.Server, workload *Workload) {
+	atomic.StoreInt32(s.ready, 0)
+	close(s.done)
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdown)
+	defer cancel()
+	if err := workload.Shutdown(ctx); err != nil {
+		log.Printf("Failed to stop all jobs: %v", err)
+	}
+	if s.reportPath != "" {
+		if err := s.WriteReport(workload); err != nil {
+			log.Printf("Failed to write final report to %q: %v", s.reportPath, err)
+		} else {
+			log.Printf("Wrote final report to %q", s.reportPath)
+		}
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to finish all requests: %v", err)
+		server.Close()
+	}
+}
+
+func (s *Server) shutdownOnSignal(server *http.Server, workload *Workload, finished chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	received := <-signals
+	log.Printf("Received %v, shutting down within %v", received, s.shutdown)
+	s.Shutdown(server, workload)
+	close(finished)
+}
@@ -0,0 +1,89 @@
+package benchmark
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_WorkloadShutdown(t *testing.T) {
+	workload := NewWorkload(&TestSessionMaker{})
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	_, err = workload.Start()
+	assert.Nil(t, err)
+	time.Sleep(1500 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.Nil(t, workload.Shutdown(ctx))
+	assert.False(t, workload.running)
+	assert.Equal(t, 0, len(workload.messages))
+	assert.True(t, workload.results.Len("abc", "read") >= 1)
+	assert.True(t, workload.results.Len("abc", "write") >= 1)
+	events := workload.Events()
+	assert.Equal(t, "shutdown", events[len(events)-1].kind)
+
+	_, err = workload.Start()
+	assert.EqualError(t, err, "Shutting down")
+	assert.Nil(t, workload.Shutdown(ctx))
+}
+
+func Test_WorkloadShutdownDeadline(t *testing.T) {
+	workload := NewWorkload(&TestSessionMaker{})
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	_, err = workload.Start()
+	assert.Nil(t, err)
+
+	// the jobs stop waiting for their next query straight away, but are in the middle of their first query after a second
+	time.Sleep(1100 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, workload.Shutdown(ctx))
+	assert.False(t, workload.running)
+}
+
+func Test_ServerShutdownStopsBackgroundGoroutines(t *testing.T) {
+	s, workload := mockServer(t)
+	s.Shutdown(&http.Server{}, workload)
+	select {
+	case <-s.done:
+	default:
+		assert.Fail(t, "The goroutines running in the background are not told to stop")
+	}
+}
+
+func Test_WriteReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	s, _ := mockServer(t)
+	workload := mockPublishedWorkload(t)
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"latency-report.html", "<!DOCTYPE html>\n"},
+		{"latency-report.md", "# Latency benchmark report for testenv\n"},
+	}
+	for _, test := range tests {
+		s.reportPath = filepath.Join(dir, test.name)
+		assert.Nil(t, s.WriteReport(workload))
+		content, err := ioutil.ReadFile(s.reportPath)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(string(content), test.prefix), test.name)
+		_, err = os.Stat(s.reportPath + ".tmp")
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	s.reportPath = filepath.Join(dir, "missing", "latency-report.html")
+	assert.NotNil(t, s.WriteReport(workload))
+}
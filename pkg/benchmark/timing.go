@@ -0,0 +1,88 @@
+package benchmark
+
+import (
+	"fmt"
+	"time"
+)
+
+// The duration of each query is broken down into the time to acquire a connection and begin the transaction, the time
+// the server took to make the result available and to stream it, as reported in the result summary, and the rest,
+// which is spent in the client and on the network. When the driver retried the query, the connection time is that of
+// the first attempt and the server and streaming times those of the last one, while the failed attempts are timed
+// separately and are not part of any of these. Queries run by other session makers, like those in tests, have no
+// breakdown.
+
+// How long the parts of a query took, in milliseconds
+type Timing struct {
+	Connection int64
+	Server     int64
+	Streaming  int64
+	Client     int64
+	Attempts   int   // the number of runs of the transaction function, including the retries
+	retrying   int64 // the time spent in failed attempts, until the next attempt started
+	measured   bool
+}
+
+var timingComponents = []string{"connection", "server", "streaming", "client"}
+
+// The timing with the client time set to the part of the total duration not spent in the other parts
+func (t Timing) withTotal(total time.Duration) Timing {
+	if t.measured {
+		t.Client = total.Milliseconds() - t.Connection - t.Server - t.Streaming - t.retrying
+		if t.Client < 0 {
+			t.Client = 0
+		}
+	}
+	return t
+}
+
+func (t Timing) components() []int64 {
+	return []int64{t.Connection, t.Server, t.Streaming, t.Client}
+}
+
+// The duration and its breakdown of each query of the job that has one, with each part as a separate column
+func (w *Workload) BreakdownFor(dbid string, verb string) (*Neo4jResult, error) {
+	if verb != "read" && verb != "write" {
+		return nil, newWorkloadError(ErrInvalid, "Invalid result verb: %s", verb)
+	}
+	result := NewNeo4jResult(append([]string{"timestamp", "total"}, timingComponents...))
+	results := w.results.For(dbid, verb)
+	for i, timing := range results.timings {
+		if timing.measured {
+			row := []interface{}{results.timestamps[i], results.durations[i]}
+			for _, value := range timing.components() {
+				row = append(row, value)
+			}
+			result.add(row)
+		}
+	}
+	return result, nil
+}
+
+// Percentiles of each part of the queries of all jobs
+func (w *Workload) BreakdownSummary() (*Neo4jResult, error) {
+	result := NewNeo4jResult([]string{"dbid", "verb", "part", "count", "p50", "p90", "p99", "max"})
+	for _, client := range w.List() {
+		for _, verb := range []string{"read", "write"} {
+			parts := make([][]int64, len(timingComponents))
+			for _, timing := range w.results.For(client.dbid, verb).timings {
+				if timing.measured {
+					for i, value := range timing.components() {
+						parts[i] = append(parts[i], value)
+					}
+				}
+			}
+			if len(parts[0]) == 0 {
+				continue
+			}
+			for i, name := range timingComponents {
+				result.add([]interface{}{client.dbid, verb, name, len(parts[i]), percentile(parts[i], 50), percentile(parts[i], 90), percentile(parts[i], 99), percentile(parts[i], 100)})
+			}
+		}
+	}
+	return result, nil
+}
+
+func breakdownTitle(dbid string, verb string) string {
+	return fmt.Sprintf("%s latency breakdown for %s", verb, dbid)
+}
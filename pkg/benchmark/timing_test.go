@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_TimingWithTotal(t *testing.T) {
+	tests := []struct {
+		timing   Timing
+		total    time.Duration
+		expected Timing
+	}{
+		{Timing{Connection: 2, Server: 5, Streaming: 1, Attempts: 1, measured: true}, 20 * time.Millisecond, Timing{Connection: 2, Server: 5, Streaming: 1, Client: 12, Attempts: 1, measured: true}},
+		{Timing{Connection: 2, Server: 5, Streaming: 1, Attempts: 1, measured: true}, 6 * time.Millisecond, Timing{Connection: 2, Server: 5, Streaming: 1, Attempts: 1, measured: true}},
+		{Timing{Connection: 2, Server: 5, Streaming: 1, Attempts: 3, retrying: 9, measured: true}, 20 * time.Millisecond, Timing{Connection: 2, Server: 5, Streaming: 1, Client: 3, Attempts: 3, retrying: 9, measured: true}},
+		{Timing{}, 20 * time.Millisecond, Timing{}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.timing.withTotal(test.total))
+	}
+}
+
+func mockTimingWorkload(t *testing.T) *Workload {
+	workload := NewWorkload(&TestSessionMaker{})
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	for i := int64(0); i < 4; i++ {
+		workload.record(Message{verb: "read", dbid: "abc", value: 10 + i, timing: Timing{Connection: 1, Server: 5 + i, Streaming: 1, Client: 3, Attempts: 1, measured: true}})
+	}
+	workload.record(Message{verb: "read", dbid: "abc", value: 7})
+	workload.record(Message{verb: "write", dbid: "abc", value: 30})
+	return workload
+}
+
+func Test_Breakdown(t *testing.T) {
+	s, _ := mockServer(t)
+	workload := mockTimingWorkload(t)
+
+	tests := []struct {
+		path       string
+		statuscode int
+		expected   string
+	}{
+		{"/stats/abc/read/breakdown", http.StatusOK, `{"Header":["timestamp","total","connection","server","streaming","client"],"Rows":[[1,10,1,5,1,3],[2,11,1,6,1,3],[3,12,1,7,1,3],[4,13,1,8,1,3]]}`},
+		{"/stats/abc/write/breakdown", http.StatusOK, `{"Header":["timestamp","total","connection","server","streaming","client"],"Rows":[]}`},
+		{"/stats/abc/other/breakdown", http.StatusBadRequest, `{"error":"Invalid result verb: other","message":"Failed to get results"}`},
+		{apiPrefix + "/stats/abc/read/breakdown", http.StatusOK, `{"Header":["timestamp","total","connection","server","streaming","client"],"Rows":[[1,10,1,5,1,3],[2,11,1,6,1,3],[3,12,1,7,1,3],[4,13,1,8,1,3]]}`},
+		{apiPrefix + "/stats/xyz/read/breakdown", http.StatusNotFound, `{"error":{"status":404,"message":"Failed to get results","detail":"Could not find client for database 'xyz'"}}`},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		if strings.HasPrefix(test.path, apiPrefix) {
+			s.apiHandler(workload)(recorder, mockRequest(test.path, nil))
+		} else {
+			s.resultsHandler(workload)(recorder, mockRequest(test.path, nil))
+		}
+		assert.Equal(t, test.statuscode, recorder.Code, test.path)
+		assert.Equal(t, test.expected, recorder.Body.String(), test.path)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.chartsHandler(workload)(recorder, mockRequest("/charts/abc/read/breakdown", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "read latency breakdown for abc")
+	for _, series := range []string{"total", "connection", "server", "streaming", "client"} {
+		assert.Contains(t, recorder.Body.String(), ">"+series+"</text>")
+	}
+}
+
+func Test_BreakdownSummary(t *testing.T) {
+	workload := mockTimingWorkload(t)
+	summary, err := workload.BreakdownSummary()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]interface{}{
+		{"abc", "read", "connection", 4, int64(1), int64(1), int64(1), int64(1)},
+		{"abc", "read", "server", 4, int64(6), int64(8), int64(8), int64(8)},
+		{"abc", "read", "streaming", 4, int64(1), int64(1), int64(1), int64(1)},
+		{"abc", "read", "client", 4, int64(3), int64(3), int64(3), int64(3)},
+	}, summary.Rows)
+
+	report, err := NewReport("testenv", workload, 10)
+	assert.Nil(t, err)
+	var markdown strings.Builder
+	assert.Nil(t, report.WriteMarkdown(&markdown))
+	assert.Contains(t, markdown.String(), "## Latency breakdown (ms)\n\n| dbid | verb | part | count | p50 | p90 | p99 | max |")
+	assert.Contains(t, markdown.String(), "| abc | read | server | 4 | 6 | 8 | 8 | 8 |")
+}
@@ -0,0 +1,276 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// While a job is running, its topology monitor runs `SHOW DATABASES` against the system database every
+// TOPOLOGY_INTERVAL seconds (default 10, zero disables it) and records each change of the role or status of a member,
+// so that latency spikes can be matched with leader switches. The first poll records the initial state of every
+// member. Later changes are also recorded as 'topology' events, which are shown in the report.
+
+const defaultTopologyInterval = 10 * time.Second
+
+const systemDatabase = "system"
+
+// The state of one member of the cluster for one database, from the time it was first seen in that state
+type TopologyChange struct {
+	Timestamp int64  `json:"timestamp"`
+	Database  string `json:"database"`
+	Address   string `json:"address"`
+	Role      string `json:"role"`   // empty if the member is no longer listed
+	Status    string `json:"status"` // the current status, or 'absent' if the member is no longer listed
+	Error     string `json:"error,omitempty"`
+}
+
+func (c TopologyChange) String() string {
+	text := fmt.Sprintf("%s on %s is %s", c.Database, c.Address, c.Status)
+	if c.Role != "" {
+		text = fmt.Sprintf("%s %s", text, c.Role)
+	}
+	if c.Error != "" {
+		text = fmt.Sprintf("%s: %s", text, c.Error)
+	}
+	return text
+}
+
+// A new leader of a database. The timestamp is that of the first poll at which the previous leader was no longer the
+// leader, which can be earlier than the poll at which the new leader was detected if there was an election in between.
+type LeaderSwitch struct {
+	Timestamp int64  `json:"timestamp"`
+	Detected  int64  `json:"detected"`
+	Database  string `json:"database"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+func (l LeaderSwitch) String() string {
+	return fmt.Sprintf("%s from %s to %s", l.Database, l.From, l.To)
+}
+
+// The topology of the cluster behind a job, as returned by GET /api/v1/jobs/{dbid}/topology
+type TopologyTimeline struct {
+	Dbid      string           `json:"dbid"`
+	LastPoll  int64            `json:"lastPoll"`
+	LastError string           `json:"lastError,omitempty"`
+	Members   []TopologyChange `json:"members"`
+	Changes   []TopologyChange `json:"changes"`
+	Switches  []LeaderSwitch   `json:"switches"`
+}
+
+type Topology struct {
+	lock      sync.Mutex
+	members   map[string]TopologyChange // the latest state of each member by database and address
+	changes   []TopologyChange
+	leaders   map[string]string // the last known leader of each database
+	lostAt    map[string]int64  // when the last known leader stopped being the leader, if there is no new leader yet
+	switches  []LeaderSwitch
+	lastPoll  int64
+	lastError string
+}
+
+func NewTopology() *Topology {
+	return &Topology{members: map[string]TopologyChange{}, changes: []TopologyChange{}, leaders: map[string]string{}, lostAt: map[string]int64{}, switches: []LeaderSwitch{}}
+}
+
+func stringIn(row []interface{}, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	if text, ok := row[index].(string); ok {
+		return text
+	}
+	return ""
+}
+
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Record the result of `SHOW DATABASES` polled at the timestamp, and return the changes and leader switches since the
+// previous poll
+func (t *Topology) Update(timestamp int64, result *Neo4jResult) ([]TopologyChange, []LeaderSwitch, error) {
+	name, address := columnIndex(result.Header, "name"), columnIndex(result.Header, "address")
+	role, status, message := columnIndex(result.Header, "role"), columnIndex(result.Header, "currentStatus"), columnIndex(result.Header, "error")
+	if name < 0 || address < 0 {
+		return nil, nil, t.Failed(timestamp, errors.New(fmt.Sprintf("Expected 'name' and 'address' columns in SHOW DATABASES result but got %v", result.Header)))
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	changes := []TopologyChange{}
+	seen := map[string]bool{}
+	leaders := map[string]string{}
+	for _, row := range result.Rows {
+		member := TopologyChange{timestamp, stringIn(row, name), stringIn(row, address), stringIn(row, role), stringIn(row, status), stringIn(row, message)}
+		key := member.Database + "@" + member.Address
+		seen[key] = true
+		if _, ok := leaders[member.Database]; !ok || member.Role == "leader" {
+			leaders[member.Database] = ""
+			if member.Role == "leader" {
+				leaders[member.Database] = member.Address
+			}
+		}
+		if previous, ok := t.members[key]; !ok || previous.Role != member.Role || previous.Status != member.Status || previous.Error != member.Error {
+			t.members[key] = member
+			changes = append(changes, member)
+		}
+	}
+	for key, previous := range t.members {
+		if !seen[key] && previous.Status != "absent" {
+			member := TopologyChange{timestamp, previous.Database, previous.Address, "", "absent", ""}
+			t.members[key] = member
+			changes = append(changes, member)
+		}
+	}
+	sortTopology(changes)
+	t.changes = append(t.changes, changes...)
+	switches := t.updateLeaders(timestamp, leaders)
+	t.switches = append(t.switches, switches...)
+	t.lastPoll = timestamp
+	t.lastError = ""
+	return changes, switches, nil
+}
+
+func (t *Topology) updateLeaders(timestamp int64, leaders map[string]string) []LeaderSwitch {
+	switches := []LeaderSwitch{}
+	for database, leader := range leaders {
+		known, ok := t.leaders[database]
+		switch {
+		case !ok || known == "":
+			t.leaders[database] = leader
+		case leader == known:
+			delete(t.lostAt, database)
+		default:
+			if _, lost := t.lostAt[database]; !lost {
+				t.lostAt[database] = timestamp
+			}
+			if leader != "" {
+				switches = append(switches, LeaderSwitch{t.lostAt[database], timestamp, database, known, leader})
+				t.leaders[database] = leader
+				delete(t.lostAt, database)
+			}
+		}
+	}
+	sort.Slice(switches, func(i, j int) bool { return switches[i].Database < switches[j].Database })
+	return switches
+}
+
+// Record that polling the topology failed, returning the error
+func (t *Topology) Failed(timestamp int64, err error) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastPoll = timestamp
+	t.lastError = err.Error()
+	return err
+}
+
+func sortTopology(members []TopologyChange) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].Timestamp != members[j].Timestamp {
+			return members[i].Timestamp < members[j].Timestamp
+		}
+		if members[i].Database != members[j].Database {
+			return members[i].Database < members[j].Database
+		}
+		return members[i].Address < members[j].Address
+	})
+}
+
+func (t *Topology) Timeline(dbid string) TopologyTimeline {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	members := []TopologyChange{}
+	for _, member := range t.members {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Database != members[j].Database {
+			return members[i].Database < members[j].Database
+		}
+		return members[i].Address < members[j].Address
+	})
+	changes := make([]TopologyChange, len(t.changes))
+	copy(changes, t.changes)
+	return TopologyTimeline{dbid, t.lastPoll, t.lastError, members, changes, t.switchesOf("")}
+}
+
+// The leader switches of the database, or of all databases if it is empty
+func (t *Topology) Switches(database string) []LeaderSwitch {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.switchesOf(database)
+}
+
+func (t *Topology) switchesOf(database string) []LeaderSwitch {
+	if database == "" {
+		return append([]LeaderSwitch{}, t.switches...)
+	}
+	switches := []LeaderSwitch{}
+	for _, leaderSwitch := range t.switches {
+		if leaderSwitch.Database == database {
+			switches = append(switches, leaderSwitch)
+		}
+	}
+	return switches
+}
+
+func (n *Neo4jJob) pollTopology(runner QuerySession, topology *Topology, timestamps TimestampMaker) ([]TopologyChange, []LeaderSwitch, error) {
+	result, err := runner.RunCypherQuery(neo4j.AccessModeRead, "SHOW DATABASES")
+	if err != nil {
+		return nil, nil, topology.Failed(timestamps.CurrentTimestamp(), err)
+	}
+	return topology.Update(timestamps.CurrentTimestamp(), result)
+}
+
+func (n *Neo4jJob) monitorTopology(ch chan Message, maker SessionMaker, topology *Topology, interval time.Duration) {
+	system := n.neo4j
+	system.database = systemDatabase
+	timestamps := maker.NewTimestampMaker()
+	runner, err := maker.NewQuerySession(system, neo4j.AccessModeRead)
+	if err != nil {
+		log.Printf("Failed to create runner for topology monitor of '%s': %v", n.dbid, err)
+		topology.Failed(timestamps.CurrentTimestamp(), err)
+		return
+	}
+	defer runner.Close()
+	log.Printf("Starting topology monitor of '%s' every %v", n.dbid, interval)
+	first := true
+	for polling := true; polling; polling = n.sleep(interval) {
+		changes, switches, err := n.pollTopology(runner, topology, timestamps)
+		if err != nil {
+			log.Printf("Failed to poll topology of '%s': %v", n.dbid, err)
+		} else if !first {
+			for _, change := range changes {
+				ch <- Message{verb: "topology", dbid: n.dbid, value: -1, message: change.String()}
+			}
+		}
+		for _, leaderSwitch := range switches {
+			if leaderSwitch.Database == n.neo4j.database {
+				log.Printf("Leader of '%s' switched %v", n.dbid, leaderSwitch)
+				ch <- Message{verb: "leader-switch", dbid: n.dbid, value: -1, message: leaderSwitch.String()}
+			}
+		}
+		first = first && err != nil
+	}
+	log.Printf("Finishing topology monitor of '%s'", n.dbid)
+}
+
+func (s *Server) apiTopologyHandler(writer http.ResponseWriter, request *http.Request, workload *Workload, job *Neo4jJob) {
+	if request.Method != http.MethodGet {
+		s.methodNotAllowed(writer, request, http.MethodGet)
+		return
+	}
+	s.writeJSON(writer, http.StatusOK, workload.TopologyFor(job.dbid).Timeline(job.dbid))
+}
@@ -0,0 +1,149 @@
+package benchmark
+
+import (
+	"context"
+	"github.com/neo4j/neo4j-go-driver/v4/neo4j"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var showDatabasesHeader = []string{"name", "address", "role", "requestedStatus", "currentStatus", "error", "default", "systemDefault"}
+
+func showDatabases(rows ...[]interface{}) *Neo4jResult {
+	result := NewNeo4jResult(showDatabasesHeader)
+	for _, row := range rows {
+		result.add(row)
+	}
+	return result
+}
+
+func member(address string, role string, status string) []interface{} {
+	return []interface{}{"neo4j", address, role, "online", status, "", true, true}
+}
+
+// Returns the results of SHOW DATABASES in turn from the system database, repeating the last one
+type TestTopologySessionMaker struct {
+	TestSessionMaker
+	lock    sync.Mutex
+	results []*Neo4jResult
+}
+
+type TestTopologySession struct {
+	TestQuerySession
+	maker *TestTopologySessionMaker
+}
+
+func (m *TestTopologySessionMaker) NewQuerySession(n Neo4j, accessMode neo4j.AccessMode) (QuerySession, error) {
+	if n.database == systemDatabase {
+		return &TestTopologySession{maker: m}, nil
+	}
+	return &TestQuerySession{}, nil
+}
+
+func (r *TestTopologySession) RunCypherQuery(accessMode neo4j.AccessMode, query string) (*Neo4jResult, error) {
+	r.maker.lock.Lock()
+	defer r.maker.lock.Unlock()
+	result := r.maker.results[0]
+	if len(r.maker.results) > 1 {
+		r.maker.results = r.maker.results[1:]
+	}
+	return result, nil
+}
+
+func Test_TopologyUpdate(t *testing.T) {
+	topology := NewTopology()
+	tests := []struct {
+		result   *Neo4jResult
+		expected []string
+	}{
+		{showDatabases(member("core-1:7687", "leader", "online"), member("core-2:7687", "follower", "online")),
+			[]string{"neo4j on core-1:7687 is online leader", "neo4j on core-2:7687 is online follower"}},
+		{showDatabases(member("core-2:7687", "follower", "online"), member("core-1:7687", "leader", "online")),
+			[]string{}},
+		{showDatabases(member("core-1:7687", "follower", "online"), member("core-2:7687", "leader", "online")),
+			[]string{"neo4j on core-1:7687 is online follower", "neo4j on core-2:7687 is online leader"}},
+		{showDatabases(member("core-2:7687", "leader", "online")),
+			[]string{"neo4j on core-1:7687 is absent"}},
+		{showDatabases(member("core-2:7687", "leader", "online")),
+			[]string{}},
+		{showDatabases(member("core-1:7687", "unknown", "offline"), member("core-2:7687", "leader", "online")),
+			[]string{"neo4j on core-1:7687 is offline unknown"}},
+	}
+	for i, test := range tests {
+		changes, _, err := topology.Update(int64(i+1), test.result)
+		assert.Nil(t, err)
+		descriptions := []string{}
+		for _, change := range changes {
+			assert.Equal(t, int64(i+1), change.Timestamp)
+			descriptions = append(descriptions, change.String())
+		}
+		assert.Equal(t, test.expected, descriptions, "poll %d", i+1)
+	}
+
+	timeline := topology.Timeline("abc")
+	assert.Equal(t, int64(6), timeline.LastPoll)
+	assert.Equal(t, 6, len(timeline.Changes))
+	assert.Equal(t, []TopologyChange{
+		{1, "neo4j", "core-1:7687", "leader", "online", ""},
+		{1, "neo4j", "core-2:7687", "follower", "online", ""},
+		{3, "neo4j", "core-1:7687", "follower", "online", ""},
+		{3, "neo4j", "core-2:7687", "leader", "online", ""},
+		{4, "neo4j", "core-1:7687", "", "absent", ""},
+		{6, "neo4j", "core-1:7687", "unknown", "offline", ""},
+	}, timeline.Changes)
+	assert.Equal(t, []TopologyChange{
+		{6, "neo4j", "core-1:7687", "unknown", "offline", ""},
+		{3, "neo4j", "core-2:7687", "leader", "online", ""},
+	}, timeline.Members)
+
+	_, _, err := topology.Update(7, NewNeo4jResult([]string{"count(n)"}))
+	assert.EqualError(t, err, "Expected 'name' and 'address' columns in SHOW DATABASES result but got [count(n)]")
+	timeline = topology.Timeline("abc")
+	assert.Equal(t, int64(7), timeline.LastPoll)
+	assert.Equal(t, err.Error(), timeline.LastError)
+	assert.Equal(t, 6, len(timeline.Changes))
+}
+
+func Test_TopologyMonitor(t *testing.T) {
+	maker := &TestTopologySessionMaker{results: []*Neo4jResult{
+		showDatabases(member("core-1:7687", "leader", "online"), member("core-2:7687", "follower", "online")),
+		showDatabases(member("core-1:7687", "follower", "online"), member("core-2:7687", "leader", "online")),
+	}}
+	workload := NewWorkload(maker)
+	workload.topologyInterval = 50 * time.Millisecond
+	err := workload.Add(NewNeo4jJob(*NewNeo4j("abc", "neo4j+s://abc-testenv.databases.neo4j.io", "neo4j", "neo4j", "secret")))
+	assert.Nil(t, err)
+	_, err = workload.Start()
+	assert.Nil(t, err)
+	time.Sleep(500 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.Nil(t, workload.Shutdown(ctx))
+
+	timeline := workload.TopologyFor("abc").Timeline("abc")
+	assert.Equal(t, 4, len(timeline.Changes))
+	assert.Equal(t, "leader", timeline.Members[1].Role)
+	events := []string{}
+	for _, event := range workload.Events() {
+		if event.kind == "topology" {
+			events = append(events, event.message)
+		}
+	}
+	assert.Equal(t, []string{"neo4j on core-1:7687 is online follower", "neo4j on core-2:7687 is online leader"}, events)
+
+	s, _ := mockServer(t)
+	recorder := httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/abc/topology", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), `{"dbid":"abc","lastPoll":`))
+	assert.Contains(t, recorder.Body.String(), `"members":[{"timestamp":2,"database":"neo4j","address":"core-1:7687","role":"follower","status":"online"}`)
+
+	recorder = httptest.NewRecorder()
+	s.apiHandler(workload)(recorder, mockRequest(apiPrefix+"/jobs/xyz/topology", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -1,15 +1,43 @@
 package benchmark
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"math"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Errors returned by the workload wrap one of these kinds, so that callers like the REST API can map them to
+// appropriate status codes using errors.Is, while the error message itself remains unchanged.
+var (
+	ErrInvalid  = errors.New("invalid")
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
+
+type workloadError struct {
+	kind    error
+	message string
+}
+
+func (e *workloadError) Error() string {
+	return e.message
+}
+
+func (e *workloadError) Unwrap() error {
+	return e.kind
+}
+
+func newWorkloadError(kind error, format string, args ...interface{}) error {
+	return &workloadError{kind, fmt.Sprintf(format, args...)}
+}
+
 type TimestampMaker interface {
 	CurrentTimestamp() int64
 }
@@ -19,42 +47,91 @@ type Result struct {
 	verb       string
 	timestamps []int64
 	durations  []int64
+	members    []int    // index in Results.members of the cluster member that served each query
+	timings    []Timing // how long the parts of each query took, if that was measured
 }
 
 type Results struct {
 	timestampMaker TimestampMaker
 	results        map[string]Result
+	members        []Member // each member only once, since there are few members and many results
+	memberIndex    map[Member]int
+	lock           *sync.RWMutex // the read loop adds results while handlers and the publisher read them
+}
+
+func NewResults(timestampMaker TimestampMaker) Results {
+	return Results{timestampMaker, make(map[string]Result), []Member{}, map[Member]int{}, &sync.RWMutex{}}
 }
 
 func (r *Results) Clear() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	r.results = make(map[string]Result)
+	r.members = []Member{}
+	r.memberIndex = map[Member]int{}
 }
 
 func (r *Results) Add(verb string, dbid string, value int64) {
+	r.AddSample(verb, dbid, value, Member{}, Timing{})
+}
+
+// Add the duration of a query served by the cluster member, with the time taken by each part of the query
+func (r *Results) AddSample(verb string, dbid string, value int64, member Member, timing Timing) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	key := fmt.Sprintf("%s:%s", verb, dbid)
 	res, ok := r.results[key]
 	if !ok {
-		res = Result{dbid, verb, []int64{}, []int64{}}
+		res = Result{dbid, verb, []int64{}, []int64{}, []int{}, []Timing{}}
+	}
+	index, ok := r.memberIndex[member]
+	if !ok {
+		index = len(r.members)
+		r.members = append(r.members, member)
+		r.memberIndex[member] = index
 	}
 	res.timestamps = append(res.timestamps, r.timestampMaker.CurrentTimestamp())
 	res.durations = append(res.durations, value)
+	res.members = append(res.members, index)
+	res.timings = append(res.timings, timing)
 	r.results[key] = res
 }
 
+// The results of the verb, whose slices are not changed by later results as they are only ever appended to
 func (r *Results) For(dbid string, verb string) Result {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
 	key := fmt.Sprintf("%s:%s", verb, dbid)
 	res, ok := r.results[key]
 	if !ok {
-		return Result{dbid, verb, []int64{}, []int64{}}
+		return Result{dbid, verb, []int64{}, []int64{}, []int{}, []Timing{}}
 	} else {
 		return res
 	}
 }
 
+// The durations of the result grouped by the cluster member that served them
+func (r *Results) ByMember(result Result) map[Member][]int64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	durations := map[Member][]int64{}
+	for i, index := range result.members {
+		member := r.members[index]
+		durations[member] = append(durations[member], result.durations[i])
+	}
+	return durations
+}
+
+// The first and last timestamp of the read and write results, which are the ones shown in tables and charts over time
 func (r *Results) MinMax() (int64, int64) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
 	min := int64(math.MaxInt64)
 	max := int64(math.MinInt64)
 	for _, results := range r.results {
+		if results.verb != "read" && results.verb != "write" {
+			continue
+		}
 		if len(results.timestamps) > 0 {
 			first := results.timestamps[0]
 			last := results.timestamps[len(results.timestamps)-1]
@@ -70,6 +147,8 @@ func (r *Results) MinMax() (int64, int64) {
 }
 
 func (r *Results) Len(dbid string, verb string) int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
 	key := fmt.Sprintf("%s:%s", verb, dbid)
 	res, ok := r.results[key]
 	if !ok {
@@ -80,32 +159,160 @@ func (r *Results) Len(dbid string, verb string) int {
 }
 
 type Message struct {
-	verb  string
-	dbid  string
-	value int64
+	verb    string
+	dbid    string
+	value   int64
+	message string
+	server  Member // the cluster member that served the query, if known
+	timing  Timing
+}
+
+// Events are notable moments during a run, like starting and stopping the workload or errors reported by a job.
+// They are used to annotate charts and reports so that latency changes can be related to what was happening.
+type Event struct {
+	timestamp int64
+	dbid      string
+	kind      string
+	message   string
 }
 
 type Workload struct {
-	runnerMaker SessionMaker
-	clients     []*Neo4jJob
-	running     bool
-	results     Results
-	done        chan struct{}
+	runnerMaker      SessionMaker
+	clients          []*Neo4jJob
+	clientsLock      sync.Mutex // guards clients, which the handlers, reloads and discovery change while others read them
+	running          bool
+	started          bool // set by the first Start, after which starting and stopping is left to the user
+	results          Results
+	events           []Event
+	eventLock        sync.Mutex
+	eventTimes       TimestampMaker
+	messages         chan Message // for jobs added while the workload is running
+	heartbeat        int64        // unix time in nanoseconds when the read loop last proved it was not stuck
+	done             chan struct{}
+	stopped          chan struct{} // closed when the read loop exits
+	closed           bool          // set by Shutdown, after which the workload cannot be started again
+	topologies       map[string]*Topology
+	topologyLock     sync.Mutex
+	integrity        map[string]*Integrity
+	retries          *Retries
+	integrityLock    sync.Mutex
+	topologyInterval time.Duration // how often each job polls the topology of its cluster, zero to disable
 }
 
 func NewWorkload(runnerMaker SessionMaker) *Workload {
 	log.Printf("Creating Neo4j Client Benchmark Service")
-	return &Workload{runnerMaker: runnerMaker, clients: []*Neo4jJob{}, results: Results{runnerMaker.NewTimestampMaker(), make(map[string]Result)}, done: make(chan struct{})}
+	return &Workload{runnerMaker: runnerMaker, clients: []*Neo4jJob{}, results: NewResults(runnerMaker.NewTimestampMaker()), events: []Event{}, eventTimes: runnerMaker.NewTimestampMaker(), done: make(chan struct{}), topologies: map[string]*Topology{}, integrity: map[string]*Integrity{}, retries: NewRetries()}
+}
+
+func (w *Workload) addEvent(dbid string, kind string, message string) int64 {
+	w.eventLock.Lock()
+	defer w.eventLock.Unlock()
+	timestamp := w.eventTimes.CurrentTimestamp()
+	w.events = append(w.events, Event{timestamp, dbid, kind, message})
+	return timestamp
+}
+
+func (w *Workload) clearEvents() {
+	w.eventLock.Lock()
+	defer w.eventLock.Unlock()
+	w.events = []Event{}
+}
+
+func (w *Workload) Events() []Event {
+	w.eventLock.Lock()
+	defer w.eventLock.Unlock()
+	return append([]Event(nil), w.events...)
+}
+
+// The jobs in the order they were added
+func (w *Workload) jobs() []*Neo4jJob {
+	w.clientsLock.Lock()
+	defer w.clientsLock.Unlock()
+	return append([]*Neo4jJob(nil), w.clients...)
 }
 
 func (w *Workload) Add(client *Neo4jJob) error {
 	log.Printf("Creating Neo4j Client Benchmark Service for %s at %s", client.neo4j.dbid, client.neo4j.neo4jAddress)
+	if err, _ := w.Find(client); err == nil {
+		return newWorkloadError(ErrConflict, "Client for database '%s' already exists", client.neo4j.dbid)
+	}
 	err := client.Check(w.runnerMaker)
 	if err != nil {
 		return err
-	} else {
-		w.clients = append(w.clients, client)
-		return nil
+	}
+	w.clientsLock.Lock()
+	if indexOf(w.clients, client) >= 0 {
+		// added by another request while this one checked the database
+		w.clientsLock.Unlock()
+		return newWorkloadError(ErrConflict, "Client for database '%s' already exists", client.neo4j.dbid)
+	}
+	w.clients = append(w.clients, client)
+	w.clientsLock.Unlock()
+	w.addEvent(client.dbid, "add", client.neo4j.neo4jAddress)
+	if w.running {
+		w.startJob(client, w.messages)
+	}
+	return nil
+}
+
+// Replace the job with the same name by a reconfigured one, keeping the results collected so far. The previous job
+// has finished when this returns, so that it no longer sends results next to those of its replacement.
+func (w *Workload) Replace(client *Neo4jJob) error {
+	log.Printf("Replacing Neo4j Client Benchmark Service for %s at %s", client.neo4j.dbid, client.neo4j.neo4jAddress)
+	if err, _ := w.Find(client); err != nil {
+		return err
+	}
+	if err := client.Check(w.runnerMaker); err != nil {
+		return err
+	}
+	w.clientsLock.Lock()
+	found := indexOf(w.clients, client)
+	if found < 0 {
+		// removed by another request while this one checked the database
+		w.clientsLock.Unlock()
+		return newWorkloadError(ErrNotFound, "Could not find client for database '%s'", client.neo4j.dbid)
+	}
+	previous := w.clients[found]
+	w.clients[found] = client
+	w.clientsLock.Unlock()
+	previous.Stop()
+	previous.workers.Wait()
+	if previous.neo4j.neo4jAddress != client.neo4j.neo4jAddress || previous.neo4j.database != client.neo4j.database {
+		w.resetIntegrity(client.dbid)
+	}
+	w.addEvent(client.dbid, "reconfigure", client.neo4j.neo4jAddress)
+	if w.running {
+		w.startJob(client, w.messages)
+	}
+	return nil
+}
+
+// Restart the running jobs that use any of the named credentials, so that they create new sessions with the rotated
+// passwords instead of failing once the previous ones are revoked
+func (w *Workload) RestartWithCredentials(names []string) {
+	rotated := map[string]bool{}
+	for _, name := range names {
+		rotated[name] = true
+	}
+	for _, client := range w.jobs() {
+		if !rotated[client.neo4j.credentials] || !client.isRunning() {
+			continue
+		}
+		log.Printf("Restarting %s with rotated credentials '%s'", client.dbid, client.neo4j.credentials)
+		client.Stop()
+		client.workers.Wait()
+		w.clientsLock.Lock()
+		found := indexOf(w.clients, client)
+		current := found >= 0 && w.clients[found] == client
+		w.clientsLock.Unlock()
+		if !current {
+			// replaced or removed by another request in the meantime
+			continue
+		}
+		w.addEvent(client.dbid, "restart", fmt.Sprintf("credentials '%s' rotated", client.neo4j.credentials))
+		if w.running {
+			w.startJob(client, w.messages)
+		}
 	}
 }
 
@@ -123,77 +330,172 @@ func indexOf(clients []*Neo4jJob, client *Neo4jJob) int {
 	return -1
 }
 
+// Remove the job, which has finished when this returns
 func (w *Workload) Remove(client *Neo4jJob) error {
 	log.Printf("Removing Neo4j Client Benchmark Service for %s", client.neo4j.dbid)
+	w.clientsLock.Lock()
 	found := indexOf(w.clients, client)
 	if found < 0 {
+		w.clientsLock.Unlock()
 		log.Printf("Could not find client for database '%s'", client.neo4j.dbid)
-		return errors.New(fmt.Sprintf("Could not find client for database '%s'", client.neo4j.dbid))
-	} else {
-		w.clients = removeAt(w.clients, found)
-		return nil
+		return newWorkloadError(ErrNotFound, "Could not find client for database '%s'", client.neo4j.dbid)
 	}
+	removed := w.clients[found]
+	w.clients = removeAt(w.clients, found)
+	w.clientsLock.Unlock()
+	removed.Stop()
+	removed.workers.Wait()
+	w.addEvent(client.dbid, "remove", "")
+	return nil
 }
 
 func (w *Workload) Find(client *Neo4jJob) (error, *Neo4jJob) {
 	log.Printf("Finding Neo4j Client Benchmark Service for %s", client.neo4j.dbid)
+	w.clientsLock.Lock()
+	defer w.clientsLock.Unlock()
 	found := indexOf(w.clients, client)
 	if found < 0 {
 		log.Printf("Could not find client for database '%s'", client.neo4j.dbid)
-		return errors.New(fmt.Sprintf("Could not find client for database '%s'", client.neo4j.dbid)), nil
+		return newWorkloadError(ErrNotFound, "Could not find client for database '%s'", client.neo4j.dbid), nil
 	} else {
 		return nil, w.clients[found]
 	}
 }
 
 func (w *Workload) List() []*Neo4jJob {
-	log.Printf("Listing %d Neo4j Client Benchmark Services", len(w.clients))
-	sorted := append([]*Neo4jJob(nil), w.clients...)
+	sorted := w.jobs()
+	log.Printf("Listing %d Neo4j Client Benchmark Services", len(sorted))
 	sort.Slice(sorted, func(i, j int) bool {
 		return strings.Compare(sorted[i].neo4j.dbid, sorted[j].neo4j.dbid) < 0
 	})
 	return sorted
 }
 
+const heartbeatInterval = 5 * time.Second
+
+func (w *Workload) beat() {
+	atomic.StoreInt64(&w.heartbeat, time.Now().UnixNano())
+}
+
+// The time since the read loop last ran, or zero if the workload is not running
+func (w *Workload) HeartbeatAge() time.Duration {
+	if !w.running {
+		return 0
+	}
+	return time.Since(time.Unix(0, atomic.LoadInt64(&w.heartbeat)))
+}
+
 func (w *Workload) readLoop(ch chan Message) {
 	log.Printf("Starting channel read loop")
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
 	for w.running {
+		w.beat()
 		select {
+		case <-ticker.C:
 		case msg := <-ch:
-			log.Printf("Got message '%s' for '%s': %v", msg.verb, msg.dbid, msg.value)
-			switch msg.verb {
-			case "read":
-				w.results.Add(msg.verb, msg.dbid, msg.value)
-			case "write":
-				w.results.Add(msg.verb, msg.dbid, msg.value)
-			}
+			w.record(msg)
 		case <-w.done:
 			log.Printf("Notified that workload is finished")
 			w.running = false
 		}
 	}
 	log.Printf("Exiting channel read loop")
+	close(w.stopped)
+}
+
+func (w *Workload) record(msg Message) {
+	log.Printf("Got message '%s' for '%s': %v", msg.verb, msg.dbid, msg.value)
+	switch msg.verb {
+	case "read":
+		w.results.AddSample(msg.verb, msg.dbid, msg.value, msg.server, msg.timing)
+	case "write":
+		w.results.AddSample(msg.verb, msg.dbid, msg.value, msg.server, msg.timing)
+	case "lag":
+		w.results.AddSample(msg.verb, msg.dbid, msg.value, msg.server, msg.timing)
+	case "causal":
+		w.results.AddSample(msg.verb, msg.dbid, msg.value, msg.server, msg.timing)
+	case "counter":
+		w.checkCounter(msg.dbid, msg.value)
+	case "contention":
+		w.results.AddSample(msg.verb, msg.dbid, msg.value, msg.server, msg.timing)
+	case "read:retry", "write:retry", "contention:retry":
+		w.retries.Add(strings.TrimSuffix(msg.verb, ":retry"), msg.dbid, msg.message, msg.value)
+	default:
+		w.addEvent(msg.dbid, msg.verb, msg.message)
+	}
+}
+
+// Record the messages left in the channel after the read loop has exited
+func (w *Workload) drain(ch chan Message) {
+	for {
+		select {
+		case msg := <-ch:
+			w.record(msg)
+		default:
+			return
+		}
+	}
 }
 
 func (w *Workload) Start() (string, error) {
+	if w.closed {
+		return "", newWorkloadError(ErrConflict, "Shutting down")
+	}
 	if !w.running {
 		ch := make(chan Message, 100)
+		w.messages = ch
+		w.stopped = make(chan struct{})
+		w.beat()
 		w.running = true
-		for _, client := range w.clients {
-			client.Start(ch, w.runnerMaker)
+		w.started = true
+		w.clearTopologies()
+		w.clearIntegrity()
+		w.retries.Clear()
+		for _, client := range w.jobs() {
+			w.startJob(client, ch)
 		}
 		w.results.Clear()
+		w.clearEvents()
+		w.addEvent("", "start", "")
 		go w.readLoop(ch)
 		return "Started", nil
 	} else {
-		return "", errors.New("Already started")
+		return "", newWorkloadError(ErrConflict, "Already started")
+	}
+}
+
+// Start the queries of the job, and its topology monitor unless that is disabled
+func (w *Workload) startJob(client *Neo4jJob, ch chan Message) {
+	client.Start(ch, w.runnerMaker)
+	if w.topologyInterval > 0 {
+		topology, interval := w.TopologyFor(client.dbid), w.topologyInterval
+		client.spawn(func() { client.monitorTopology(ch, w.runnerMaker, topology, interval) })
+	}
+}
+
+// The topology of the cluster behind the job, which is kept when the job is removed or replaced, like its results
+func (w *Workload) TopologyFor(dbid string) *Topology {
+	w.topologyLock.Lock()
+	defer w.topologyLock.Unlock()
+	topology, ok := w.topologies[dbid]
+	if !ok {
+		topology = NewTopology()
+		w.topologies[dbid] = topology
 	}
+	return topology
+}
+
+func (w *Workload) clearTopologies() {
+	w.topologyLock.Lock()
+	defer w.topologyLock.Unlock()
+	w.topologies = map[string]*Topology{}
 }
 
 func (w *Workload) maxDurationCount() int {
 	max := 0
 	for _, verb := range []string{"read", "write"} {
-		for _, client := range w.clients {
+		for _, client := range w.jobs() {
 			count := w.results.Len(client.dbid, verb)
 			log.Printf("There were %d durations for %s queries on %s", count, verb, client.dbid)
 			if max < count {
@@ -213,22 +515,59 @@ func (w *Workload) WaitForAtLeast(threshold int) (string, error) {
 	return fmt.Sprintf("%d", w.maxDurationCount()), nil
 }
 
+// Stop all jobs and wait until they have closed their sessions and all their results are recorded
 func (w *Workload) Stop() (string, error) {
 	if w.running {
-		for _, client := range w.clients {
-			client.Stop()
-		}
-		w.done <- struct{}{}
+		w.stopJobs(context.Background())
+		w.addEvent("", "stop", "")
 		return "Stopped", nil
 	} else {
-		return "", errors.New("Already stopped")
+		return "", newWorkloadError(ErrConflict, "Already stopped")
+	}
+}
+
+// Stop all jobs for good and wait until they have closed their sessions and all their results are recorded. If the
+// context ends first, the results recorded so far are kept and the context error is returned.
+func (w *Workload) Shutdown(ctx context.Context) error {
+	w.closed = true
+	if !w.running {
+		return nil
+	}
+	err := w.stopJobs(ctx)
+	w.addEvent("", "shutdown", "")
+	return err
+}
+
+func (w *Workload) stopJobs(ctx context.Context) error {
+	jobs := w.jobs()
+	for _, client := range jobs {
+		client.Stop()
+	}
+	finished := make(chan struct{})
+	go func() {
+		for _, client := range jobs {
+			client.workers.Wait()
+		}
+		close(finished)
+	}()
+	var err error
+	select {
+	case <-finished:
+		log.Printf("All jobs have finished")
+	case <-ctx.Done():
+		err = ctx.Err()
+		log.Printf("Not all jobs finished: %v", err)
 	}
+	w.done <- struct{}{}
+	<-w.stopped
+	w.drain(w.messages)
+	return err
 }
 
 func (w *Workload) Results() (*Neo4jResult, error) {
 	result := NewNeo4jResult([]string{"dbid", "verb", "count"})
 	for _, verb := range []string{"read", "write"} {
-		for _, client := range w.clients {
+		for _, client := range w.jobs() {
 			count := w.results.Len(client.dbid, verb)
 			result.add([]interface{}{client.dbid, verb, count})
 		}
@@ -238,7 +577,7 @@ func (w *Workload) Results() (*Neo4jResult, error) {
 
 func (w *Workload) ResultsFor(dbid string, verb string) (*Neo4jResult, error) {
 	if verb != "read" && verb != "write" {
-		return nil, errors.New("Invalid result verb: " + verb)
+		return nil, newWorkloadError(ErrInvalid, "Invalid result verb: %s", verb)
 	}
 	result := NewNeo4jResult([]string{"timestamp", "duration"})
 	results := w.results.For(dbid, verb)
@@ -249,9 +588,200 @@ func (w *Workload) ResultsFor(dbid string, verb string) (*Neo4jResult, error) {
 	return result, nil
 }
 
+// Nearest-rank percentile of the values, where p is in the range 0-100
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// Group the durations into windows of the specified number of seconds, keyed by the first timestamp of each window
+func windowsOf(result Result, min int64, window int64) map[int64][]int64 {
+	windows := map[int64][]int64{}
+	for i, timestamp := range result.timestamps {
+		start := min + (timestamp-min)/window*window
+		windows[start] = append(windows[start], result.durations[i])
+	}
+	return windows
+}
+
+func sortedKeys(windows map[int64][]int64) []int64 {
+	keys := make([]int64, 0, len(windows))
+	for key := range windows {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func (w *Workload) PercentilesFor(dbid string, verb string, window int64) (*Neo4jResult, error) {
+	if verb != "read" && verb != "write" {
+		return nil, newWorkloadError(ErrInvalid, "Invalid result verb: %s", verb)
+	}
+	if window < 1 {
+		return nil, newWorkloadError(ErrInvalid, "Invalid percentile window: %d", window)
+	}
+	result := NewNeo4jResult([]string{"timestamp", "p50", "p90", "p99", "max"})
+	min, _ := w.results.MinMax()
+	windows := windowsOf(w.results.For(dbid, verb), min, window)
+	for _, timestamp := range sortedKeys(windows) {
+		durations := windows[timestamp]
+		result.add([]interface{}{timestamp, percentile(durations, 50), percentile(durations, 90), percentile(durations, 99), percentile(durations, 100)})
+	}
+	return result, nil
+}
+
+// Like ResultsTable, but with one column per dbid and verb containing the specified percentile for each time window,
+// which is nil for windows without results for that dbid and verb, so that charts show a gap instead of a latency
+func (w *Workload) PercentilesTable(p float64, window int64) (*Neo4jResult, error) {
+	if p <= 0 || p > 100 {
+		return nil, newWorkloadError(ErrInvalid, "Invalid percentile: %v", p)
+	}
+	if window < 1 {
+		return nil, newWorkloadError(ErrInvalid, "Invalid percentile window: %d", window)
+	}
+	columns := []string{"timestamp"}
+	min, _ := w.results.MinMax()
+	rows := map[int64][]interface{}{}
+	clients := w.jobs()
+	for _, verb := range []string{"read", "write"} {
+		for _, client := range clients {
+			columns = append(columns, fmt.Sprintf("%s:%s", verb, client.dbid))
+			for timestamp, durations := range windowsOf(w.results.For(client.dbid, verb), min, window) {
+				row, ok := rows[timestamp]
+				if !ok {
+					row = make([]interface{}, 2*len(clients)+1)
+					row[0] = timestamp
+					rows[timestamp] = row
+				}
+				row[len(columns)-1] = percentile(durations, p)
+			}
+		}
+	}
+	result := NewNeo4jResult(columns)
+	timestamps := make([]int64, 0, len(rows))
+	for timestamp := range rows {
+		timestamps = append(timestamps, timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	for _, timestamp := range timestamps {
+		result.add(rows[timestamp])
+	}
+	return result, nil
+}
+
+// Summary of the latency distribution and error count for each database and verb over the whole run
+func (w *Workload) LatencySummary() (*Neo4jResult, error) {
+	result := NewNeo4jResult([]string{"dbid", "verb", "count", "errors", "min", "p50", "p90", "p99", "max"})
+	errorCounts := map[string]int{}
+	for _, event := range w.Events() {
+		errorCounts[fmt.Sprintf("%s:%s", event.kind, event.dbid)]++
+	}
+	for _, client := range w.List() {
+		for _, verb := range []string{"read", "write"} {
+			durations := w.results.For(client.dbid, verb).durations
+			errors := errorCounts[fmt.Sprintf("%s:error:%s", verb, client.dbid)]
+			result.add([]interface{}{client.dbid, verb, len(durations), errors, percentile(durations, 0), percentile(durations, 50), percentile(durations, 90), percentile(durations, 99), percentile(durations, 100)})
+		}
+	}
+	return result, nil
+}
+
+// The latest latencies of one job, for publishing outside the service
+type LatencySnapshot struct {
+	ReadP50     int64
+	ReadP99     int64
+	WriteP50    int64
+	WriteP99    int64
+	ErrorRate   float64 // failed reads and writes as a fraction of all reads and writes in the window
+	LastSuccess int64   // timestamp of the last successful query, or zero if there was none
+}
+
+// Percentiles and error rate of the read and write queries within the window of seconds up to the last of them
+func (w *Workload) Snapshot(dbid string, window int64) LatencySnapshot {
+	snapshot := LatencySnapshot{}
+	results := []Result{w.results.For(dbid, "read"), w.results.For(dbid, "write")}
+	for _, result := range results {
+		if count := len(result.timestamps); count > 0 && result.timestamps[count-1] > snapshot.LastSuccess {
+			snapshot.LastSuccess = result.timestamps[count-1]
+		}
+	}
+	last := snapshot.LastSuccess
+	failed := []int64{}
+	for _, event := range w.Events() {
+		if event.dbid == dbid && (event.kind == "read:error" || event.kind == "write:error") {
+			failed = append(failed, event.timestamp)
+			if event.timestamp > last {
+				last = event.timestamp
+			}
+		}
+	}
+	failures := 0
+	for _, timestamp := range failed {
+		if timestamp > last-window {
+			failures++
+		}
+	}
+	queries := failures
+	for _, result := range results {
+		latest := []int64{}
+		for i, timestamp := range result.timestamps {
+			if timestamp > last-window {
+				latest = append(latest, result.durations[i])
+			}
+		}
+		queries += len(latest)
+		if len(latest) == 0 {
+			continue
+		}
+		if result.verb == "read" {
+			snapshot.ReadP50, snapshot.ReadP99 = percentile(latest, 50), percentile(latest, 99)
+		} else {
+			snapshot.WriteP50, snapshot.WriteP99 = percentile(latest, 50), percentile(latest, 99)
+		}
+	}
+	if queries > 0 {
+		snapshot.ErrorRate = float64(failures) / float64(queries)
+	}
+	return snapshot
+}
+
+// Summary of all errors reported during the run, grouped by database and kind of error
+func (w *Workload) ErrorSummary() (*Neo4jResult, error) {
+	result := NewNeo4jResult([]string{"dbid", "kind", "count", "first", "last", "message"})
+	index := map[string]int{}
+	for _, event := range w.Events() {
+		if !strings.HasSuffix(event.kind, ":error") {
+			continue
+		}
+		key := fmt.Sprintf("%s:%s", event.kind, event.dbid)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(result.Rows)
+			result.add([]interface{}{event.dbid, event.kind, 1, event.timestamp, event.timestamp, event.message})
+		} else {
+			row := result.Rows[i]
+			row[2] = row[2].(int) + 1
+			row[4] = event.timestamp
+			row[5] = event.message
+		}
+	}
+	return result, nil
+}
+
 func (w *Workload) CountsFor(dbid string, verb string) (int, error) {
 	if verb != "read" && verb != "write" {
-		return -1, errors.New("Invalid result verb: " + verb)
+		return -1, newWorkloadError(ErrInvalid, "Invalid result verb: %s", verb)
 	}
 	count := w.results.Len(dbid, verb)
 	return count, nil
@@ -259,12 +789,16 @@ func (w *Workload) CountsFor(dbid string, verb string) (int, error) {
 
 func (w *Workload) ResultsTable() (*Neo4jResult, error) {
 	columns := []string{"timestamp"}
-	for _, client := range w.clients {
+	clients := w.jobs()
+	for _, client := range clients {
 		columns = append(columns, fmt.Sprintf("%s:%s", "read", client.dbid))
 		columns = append(columns, fmt.Sprintf("%s:%s", "write", client.dbid))
 	}
 	result := NewNeo4jResult(columns)
 	min, max := w.results.MinMax()
+	if min > max {
+		return result, nil
+	}
 	count := int(max - min + 1)
 	data := [][]interface{}{}
 	add_data := func(column_index int, result Result) {
@@ -272,7 +806,7 @@ func (w *Workload) ResultsTable() (*Neo4jResult, error) {
 			duration := result.durations[i]
 			offset := int(timestamp - min)
 			for len(data) < offset+1 {
-				row := make([]interface{}, 2*len(w.clients)+1)
+				row := make([]interface{}, 2*len(clients)+1)
 				row[0] = min + int64(len(data))
 				for x := 1; x < len(row); x++ {
 					row[x] = int64(0)
@@ -282,7 +816,7 @@ func (w *Workload) ResultsTable() (*Neo4jResult, error) {
 			data[offset][column_index] = duration
 		}
 	}
-	for client_index, client := range w.clients {
+	for client_index, client := range clients {
 		add_data(client_index*2+1, w.results.For(client.dbid, "read"))
 		add_data(client_index*2+2, w.results.For(client.dbid, "write"))
 	}